@@ -0,0 +1,321 @@
+// bookmark actions on the selected list item
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ieroNo47/gopaper/internal/cache"
+	"github.com/ieroNo47/gopaper/internal/instapaper"
+)
+
+type actionKeyMap struct {
+	archive  key.Binding
+	star     key.Binding
+	move     key.Binding
+	delete   key.Binding
+	moveHere key.Binding
+	confirm  key.Binding
+	cancel   key.Binding
+}
+
+var actionKeys = actionKeyMap{
+	archive: key.NewBinding(
+		key.WithKeys("e"),
+		key.WithHelp("e", "archive"),
+	),
+	star: key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "star"),
+	),
+	move: key.NewBinding(
+		key.WithKeys("m"),
+		key.WithHelp("m", "move"),
+	),
+	delete: key.NewBinding(
+		key.WithKeys("x"),
+		key.WithHelp("x", "delete"),
+	),
+	moveHere: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("enter", "move here"),
+	),
+	confirm: key.NewBinding(
+		key.WithKeys("y", "enter"),
+		key.WithHelp("y", "confirm"),
+	),
+	cancel: key.NewBinding(
+		key.WithKeys("esc", "n"),
+		key.WithHelp("esc", "cancel"),
+	),
+}
+
+type bookmarkAction int
+
+const (
+	archiveAction bookmarkAction = iota
+	unarchiveAction
+	starAction
+	unstarAction
+	moveAction
+	deleteAction
+)
+
+func (a bookmarkAction) String() string {
+	return [...]string{"archive", "unarchive", "star", "unstar", "move", "delete"}[a]
+}
+
+// pendingAction is an action sent to the API, with what is needed to undo it in the list
+type pendingAction struct {
+	// old is the item before the optimistic update, index its position in the list
+	// of the folder with folderID
+	old      item
+	index    int
+	folderID string
+	removed  bool
+}
+
+// actionDoneMsg has the bookmark after an action, deleted bookmarks have only the ID.
+// folderID is where the bookmark is now, "" after starring or deleting.
+type actionDoneMsg struct {
+	action   bookmarkAction
+	bookmark instapaper.Bookmark
+	folderID string
+}
+
+// actionErrMsg has the failed action, folderID is the target of a move
+type actionErrMsg struct {
+	action     bookmarkAction
+	bookmarkID int64
+	folderID   int64
+	err        error
+}
+
+// runAction sends an action to the API. folderID is the target of a move.
+func runAction(ctx context.Context, client instapaper.Client, c *cache.Cache, action bookmarkAction, bookmark instapaper.Bookmark, folderID int64) tea.Cmd {
+	return func() tea.Msg {
+		var updated instapaper.Bookmark
+		var err error
+		target := ""
+		switch action {
+		case archiveAction:
+			updated, err = client.ArchiveBookmark(ctx, bookmark.BookmarkID)
+			target = instapaper.FolderArchive
+		case unarchiveAction:
+			updated, err = client.UnarchiveBookmark(ctx, bookmark.BookmarkID)
+			target = instapaper.FolderUnread
+		case starAction:
+			updated, err = client.StarBookmark(ctx, bookmark.BookmarkID)
+		case unstarAction:
+			updated, err = client.UnstarBookmark(ctx, bookmark.BookmarkID)
+		case moveAction:
+			updated, err = client.MoveBookmark(ctx, bookmark.BookmarkID, folderID)
+			target = strconv.FormatInt(folderID, 10)
+		case deleteAction:
+			err = client.DeleteBookmark(ctx, bookmark.BookmarkID)
+			updated = instapaper.Bookmark{BookmarkID: bookmark.BookmarkID}
+			if err == nil {
+				_ = c.Remove(bookmark.BookmarkID)
+			}
+		}
+		if err != nil {
+			return actionErrMsg{action: action, bookmarkID: bookmark.BookmarkID, folderID: folderID, err: fmt.Errorf("failed to %v bookmark: %w", action, err)}
+		}
+		return actionDoneMsg{action: action, bookmark: updated, folderID: target}
+	}
+}
+
+// startAction updates the list right away and sends the action.
+// The item is put back if the action fails, see rollbackAction.
+func (m *model) startAction(action bookmarkAction, i item, folderID int64) tea.Cmd {
+	if _, ok := m.pending[i.ID()]; ok {
+		// wait for the previous action on the bookmark
+		return nil
+	}
+	index := m.itemIndex(i.ID())
+	if index < 0 {
+		return nil
+	}
+	pending := pendingAction{old: i, index: index, folderID: m.folderID}
+	var cmd tea.Cmd
+	switch {
+	case action == starAction || action == unstarAction:
+		bookmark := i.bookmark
+		bookmark.Starred = "0"
+		if action == starAction {
+			bookmark.Starred = "1"
+		}
+		if action == unstarAction && m.folderID == instapaper.FolderStarred {
+			pending.removed = true
+			m.list.RemoveItem(index)
+		} else {
+			cmd = m.list.SetItem(index, newItem(bookmark))
+		}
+	case action == moveAction && strconv.FormatInt(folderID, 10) == m.folderID:
+		// already there
+		return nil
+	default:
+		// the bookmark leaves the listed folder
+		pending.removed = true
+		m.list.RemoveItem(index)
+	}
+	m.pending[i.ID()] = pending
+	return tea.Batch(cmd, runAction(m.ctx, m.client, m.cache, action, i.bookmark, folderID))
+}
+
+// finishAction applies a successful action to the libraries and the cache
+func (m *model) finishAction(msg actionDoneMsg) tea.Cmd {
+	pending := m.pending[msg.bookmark.BookmarkID]
+	delete(m.pending, msg.bookmark.BookmarkID)
+	bookmarkID := msg.bookmark.BookmarkID
+	// the action failed before and was taken again
+	m.dismiss(actionKind(msg.action, bookmarkID))
+	for folderID, library := range m.libraries {
+		changed := false
+		switch {
+		case msg.action == deleteAction:
+			changed = library.Remove(bookmarkID)
+		case folderID == msg.folderID:
+			library.Add(msg.bookmark)
+			changed = true
+		case folderID == instapaper.FolderStarred && msg.action == starAction:
+			library.Add(msg.bookmark)
+			changed = true
+		case folderID == instapaper.FolderStarred && msg.action == unstarAction:
+			changed = library.Remove(bookmarkID)
+		case msg.folderID != "" && folderID != instapaper.FolderStarred:
+			// archived, unarchived or moved out of this folder
+			changed = library.Remove(bookmarkID)
+		default:
+			changed = library.Update(msg.bookmark)
+		}
+		if changed {
+			_ = m.cache.SaveBookmarks(folderID, library.Bookmarks())
+		}
+	}
+	m.updateBookmarkCount()
+	m.table.SetRows(m.getTagRows())
+	if pending.removed {
+		return nil
+	}
+	// the API returns the bookmark as it is now
+	return m.updateBookmark(msg.bookmark)
+}
+
+// rollbackAction puts the item back the way it was before a failed action
+func (m *model) rollbackAction(msg actionErrMsg) tea.Cmd {
+	m.notify(actionKind(msg.action, msg.bookmarkID), msg.err, func(m *model) tea.Cmd {
+		// the item is back in the list unless the folder was switched since
+		index := m.itemIndex(msg.bookmarkID)
+		if index < 0 {
+			return nil
+		}
+		return m.startAction(msg.action, m.list.Items()[index].(item), msg.folderID)
+	})
+	pending, ok := m.pending[msg.bookmarkID]
+	if !ok {
+		return nil
+	}
+	delete(m.pending, msg.bookmarkID)
+	if pending.folderID != m.folderID {
+		// the libraries and the cache are only updated once an action succeeds,
+		// so the folder lists the bookmark again when it is shown
+		return nil
+	}
+	// a sync may have listed the bookmark again
+	if index := m.itemIndex(msg.bookmarkID); index >= 0 {
+		return m.list.SetItem(index, pending.old)
+	}
+	if pending.removed {
+		return m.list.InsertItem(min(pending.index, len(m.list.Items())), pending.old)
+	}
+	return nil
+}
+
+// moveTo runs the action for the folder picked in the folders table while moving an item.
+// Home and Archive are the same as unarchiving and archiving, Starred stars the bookmark.
+func (m *model) moveTo(f folder) tea.Cmd {
+	i := *m.moving
+	m.moving = nil
+	m.state = bookmarksView
+	switch f.id {
+	case instapaper.FolderStarred:
+		return m.startAction(starAction, i, 0)
+	case instapaper.FolderArchive:
+		if m.folderID == instapaper.FolderArchive {
+			return nil
+		}
+		return m.startAction(archiveAction, i, 0)
+	case instapaper.FolderUnread:
+		if m.folderID == instapaper.FolderUnread {
+			return nil
+		}
+		return m.startAction(unarchiveAction, i, 0)
+	}
+	folderID, err := strconv.ParseInt(f.id, 10, 64)
+	if err != nil {
+		return nil
+	}
+	return m.startAction(moveAction, i, folderID)
+}
+
+// updateActions handles the action keys in the bookmarks list. It reports whether the key was used.
+func (m *model) updateActions(msg tea.KeyMsg) (tea.Cmd, bool) {
+	if m.confirmDelete != nil {
+		i := *m.confirmDelete
+		m.confirmDelete = nil
+		if key.Matches(msg, actionKeys.confirm) {
+			return m.startAction(deleteAction, i, 0), true
+		}
+		return nil, true
+	}
+	i, ok := m.list.SelectedItem().(item)
+	if !ok || !m.ready {
+		return nil, false
+	}
+	switch {
+	case key.Matches(msg, actionKeys.archive):
+		if m.folderID == instapaper.FolderArchive {
+			return m.startAction(unarchiveAction, i, 0), true
+		}
+		return m.startAction(archiveAction, i, 0), true
+	case key.Matches(msg, actionKeys.star):
+		if i.Starred() {
+			return m.startAction(unstarAction, i, 0), true
+		}
+		return m.startAction(starAction, i, 0), true
+	case key.Matches(msg, actionKeys.delete):
+		m.confirmDelete = &i
+		return nil, true
+	case key.Matches(msg, actionKeys.move):
+		// the folder is picked in the folders table
+		m.moving = &i
+		m.state = foldersView
+		return nil, true
+	}
+	return nil, false
+}
+
+// actionStatus asks for confirmations, failed actions are notifications
+func (m model) actionStatus() string {
+	switch {
+	case m.confirmDelete != nil:
+		return fmt.Sprintf(" • delete %q? y/n", m.confirmDelete.bookmark.Title)
+	case m.moving != nil:
+		return fmt.Sprintf(" • move %q to…", m.moving.bookmark.Title)
+	}
+	return ""
+}
+
+// itemIndex returns the position of a bookmark in the list, or -1
+func (m model) itemIndex(bookmarkID int64) int {
+	for i, li := range m.list.Items() {
+		if li.(item).ID() == bookmarkID {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,286 @@
+// add bookmark dialog
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ieroNo47/gopaper/internal/instapaper"
+)
+
+var addBookmarkStyle = lipgloss.NewStyle().
+	Margin(0).
+	Padding(0).
+	BorderStyle(lipgloss.RoundedBorder()).
+	BorderForeground(lipgloss.Color("5")).
+	MarginBackground(lipgloss.Color("5"))
+
+var addBookmarkFormStyle = lipgloss.NewStyle().
+	Padding(1, 2).
+	BorderStyle(lipgloss.RoundedBorder()).
+	BorderForeground(lipgloss.Color("5"))
+
+type addBookmarkKeyMap struct {
+	next   key.Binding
+	prev   key.Binding
+	paste  key.Binding
+	submit key.Binding
+	cancel key.Binding
+}
+
+var addBookmarkKeys = addBookmarkKeyMap{
+	next: key.NewBinding(
+		key.WithKeys("tab", "down"),
+		key.WithHelp("tab", "next field"),
+	),
+	prev: key.NewBinding(
+		key.WithKeys("shift+tab", "up"),
+		key.WithHelp("shift+tab", "previous field"),
+	),
+	paste: key.NewBinding(
+		key.WithKeys("ctrl+v"),
+		key.WithHelp("ctrl+v", "paste"),
+	),
+	submit: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("enter", "save"),
+	),
+	cancel: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "cancel"),
+	),
+}
+
+// readClipboard is replaced in tests, which must not depend on the clipboard of the machine
+var readClipboard = clipboard.ReadAll
+
+// clipboardURLMsg has a URL found in the clipboard when the dialog was opened
+type clipboardURLMsg string
+
+// bookmarkAddedMsg has the saved bookmark and the folder it was saved to
+type bookmarkAddedMsg struct {
+	bookmark instapaper.Bookmark
+	folderID string
+}
+
+type addBookmarkErrMsg struct {
+	err error
+}
+
+// pasteClipboardURL prefills the URL field if the clipboard has a web address
+func pasteClipboardURL() tea.Msg {
+	text, err := readClipboard()
+	if err != nil {
+		// e.g. no clipboard utility installed, the URL has to be typed
+		return nil
+	}
+	text = strings.TrimSpace(text)
+	if u, err := url.Parse(text); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil
+	}
+	return clipboardURLMsg(text)
+}
+
+func addBookmark(ctx context.Context, client instapaper.Client, params instapaper.AddBookmarkParams) tea.Cmd {
+	return func() tea.Msg {
+		if path, ok := localFile(params.URL); ok {
+			file, err := fileBookmark(path)
+			if err != nil {
+				return addBookmarkErrMsg{fmt.Errorf("failed to send file: %w", err)}
+			}
+			file.Title = cmp.Or(params.Title, file.Title)
+			file.Description = params.Description
+			file.FolderID = params.FolderID
+			params = file
+		}
+		bookmark, err := client.AddBookmark(ctx, params)
+		if err != nil {
+			if errors.Is(err, instapaper.ErrInvalidURL) {
+				err = fmt.Errorf("%q is not a valid URL", params.URL)
+			}
+			return addBookmarkErrMsg{fmt.Errorf("failed to save bookmark: %w", err)}
+		}
+		folderID := instapaper.FolderUnread
+		if params.FolderID != 0 {
+			folderID = strconv.FormatInt(params.FolderID, 10)
+		}
+		return bookmarkAddedMsg{bookmark: bookmark, folderID: folderID}
+	}
+}
+
+// addBookmarkModel is a form to save a URL or a local file, optionally with a title, description and folder
+type addBookmarkModel struct {
+	ctx    context.Context
+	client instapaper.Client
+	// inputs are the URL, title, description and folder fields in that order
+	inputs  []textinput.Model
+	focused int
+	// folders are the user-created folders, suggested in the folder field
+	folders []folder
+	saving  bool
+	err     error
+	width   int
+	height  int
+}
+
+const (
+	urlField = iota
+	titleField
+	descriptionField
+	folderField
+)
+
+func newAddBookmarkModel() addBookmarkModel {
+	m := addBookmarkModel{inputs: make([]textinput.Model, 4)}
+	for i := range m.inputs {
+		m.inputs[i] = textinput.New()
+	}
+	m.inputs[urlField].Prompt = "URL or file: "
+	m.inputs[urlField].Placeholder = "https://"
+	m.inputs[titleField].Prompt = "Title:       "
+	m.inputs[titleField].Placeholder = "optional, taken from the page or file"
+	m.inputs[descriptionField].Prompt = "Description: "
+	m.inputs[descriptionField].Placeholder = "optional"
+	m.inputs[folderField].Prompt = "Folder:      "
+	m.inputs[folderField].Placeholder = "Home"
+	m.inputs[folderField].ShowSuggestions = true
+	// tab moves to the next field
+	m.inputs[folderField].KeyMap.AcceptSuggestion = key.NewBinding(key.WithKeys("right"))
+	return m
+}
+
+// open resets the form. The folder field starts with the listed folder if it is user-created.
+func (m addBookmarkModel) open(ctx context.Context, client instapaper.Client, folders []folder, folderID string) (addBookmarkModel, tea.Cmd) {
+	m.ctx = ctx
+	m.client = client
+	m.folders = folders
+	m.saving = false
+	m.err = nil
+	titles := []string{}
+	for i := range m.inputs {
+		m.inputs[i].Reset()
+		m.inputs[i].Blur()
+	}
+	for _, f := range folders {
+		titles = append(titles, f.title)
+		if f.id == folderID {
+			m.inputs[folderField].SetValue(f.title)
+		}
+	}
+	m.inputs[folderField].SetSuggestions(titles)
+	m.focused = urlField
+	return m, tea.Batch(m.inputs[urlField].Focus(), pasteClipboardURL)
+}
+
+func (m *addBookmarkModel) setSize(width, height int) {
+	m.width = width
+	m.height = height
+	for i := range m.inputs {
+		m.inputs[i].Width = width / 2
+	}
+}
+
+func (m addBookmarkModel) Update(msg tea.Msg) (addBookmarkModel, tea.Cmd) {
+	var cmd tea.Cmd
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if m.saving {
+			return m, nil
+		}
+		switch {
+		case key.Matches(msg, addBookmarkKeys.next):
+			return m.focus((m.focused + 1) % len(m.inputs))
+		case key.Matches(msg, addBookmarkKeys.prev):
+			return m.focus((m.focused + len(m.inputs) - 1) % len(m.inputs))
+		case key.Matches(msg, addBookmarkKeys.submit):
+			return m.submit()
+		}
+	case clipboardURLMsg:
+		if m.inputs[urlField].Value() == "" {
+			m.inputs[urlField].SetValue(string(msg))
+		}
+		return m, nil
+	case addBookmarkErrMsg:
+		m.saving = false
+		m.err = msg.err
+		return m, nil
+	}
+	// keys and the paste messages of the text input
+	m.inputs[m.focused], cmd = m.inputs[m.focused].Update(msg)
+	return m, cmd
+}
+
+// focus moves the cursor to a field
+func (m addBookmarkModel) focus(field int) (addBookmarkModel, tea.Cmd) {
+	m.inputs[m.focused].Blur()
+	m.focused = field
+	return m, m.inputs[field].Focus()
+}
+
+// submit saves the bookmark if the form is valid
+func (m addBookmarkModel) submit() (addBookmarkModel, tea.Cmd) {
+	params := instapaper.AddBookmarkParams{
+		URL:         strings.TrimSpace(m.inputs[urlField].Value()),
+		Title:       strings.TrimSpace(m.inputs[titleField].Value()),
+		Description: strings.TrimSpace(m.inputs[descriptionField].Value()),
+	}
+	if params.URL == "" {
+		m.err = errors.New("the URL or file is required")
+		return m.focus(urlField)
+	}
+	if title := strings.TrimSpace(m.inputs[folderField].Value()); title != "" && !strings.EqualFold(title, "Home") {
+		f, ok := m.findFolder(title)
+		if !ok {
+			m.err = fmt.Errorf("no folder %q", title)
+			return m.focus(folderField)
+		}
+		params.FolderID, _ = strconv.ParseInt(f.id, 10, 64)
+	}
+	m.saving = true
+	m.err = nil
+	return m, addBookmark(m.ctx, m.client, params)
+}
+
+func (m addBookmarkModel) findFolder(title string) (folder, bool) {
+	for _, f := range m.folders {
+		if strings.EqualFold(f.title, title) {
+			return f, true
+		}
+	}
+	return folder{}, false
+}
+
+func (m addBookmarkModel) View() string {
+	status := ""
+	switch {
+	case m.saving:
+		status = "Saving..."
+	case m.err != nil:
+		status = errStyle.Width(m.width / 2).Render(m.err.Error())
+	}
+	fields := []string{loginTitleStyle.Render("Add bookmark")}
+	for _, input := range m.inputs {
+		fields = append(fields, input.View())
+	}
+	fields = append(fields, "", status)
+	form := addBookmarkFormStyle.Render(lipgloss.JoinVertical(lipgloss.Left, fields...))
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, form)
+}
+
+func (m addBookmarkModel) ShortHelp() []key.Binding {
+	return []key.Binding{addBookmarkKeys.next, addBookmarkKeys.paste, addBookmarkKeys.submit, addBookmarkKeys.cancel}
+}
+
+func (m addBookmarkModel) FullHelp() [][]key.Binding {
+	return [][]key.Binding{{addBookmarkKeys.next, addBookmarkKeys.prev}, {addBookmarkKeys.paste, addBookmarkKeys.submit, addBookmarkKeys.cancel}}
+}
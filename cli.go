@@ -0,0 +1,226 @@
+// gopaper subcommands
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/ieroNo47/gopaper/internal/cache"
+	"github.com/ieroNo47/gopaper/internal/instapaper"
+	"github.com/ieroNo47/gopaper/internal/tokenstore"
+	"golang.org/x/term"
+)
+
+const usage = `Usage: gopaper [flags] [command]
+
+Without a command the TUI is started.
+
+Commands:
+  login                      log in and store the access token
+  logout                     delete the stored access token and the cached bookmarks
+  list [--folder f] [--limit n] [--all]
+                             list the bookmarks of a folder: unread (default), starred,
+                             archive or the ID or title of a folder. --all lists every
+                             bookmark instead of the newest n (25 by default)
+  add [--title t] [--description d] [--folder f] <url>
+                             save a URL
+  send [--title t] [--folder f] <file>
+                             save a Markdown, HTML or text file as a private bookmark,
+                             titled by its first heading or the file name
+  newsletters [--folder f] <file>...
+                             import emails from .eml files and mbox archives as private
+                             bookmarks, titled by the subject and tagged with the sender.
+                             Emails imported before and emails that cannot be read are
+                             skipped
+  import [--format f] [--dry-run] <file>
+                             import the bookmarks exported from another app with their
+                             tags, folders and archived and starred state. The format is
+                             netscape (browsers), pocket, wallabag, omnivore or csv, and
+                             is detected from the file by default. Saved URLs are skipped
+                             and an interrupted import continues where it stopped.
+                             --dry-run only reports what would be imported
+  archive <id>, unarchive <id>
+                             move a bookmark to or out of the archive
+  star <id>, unstar <id>     star or unstar a bookmark
+  move <id> <folder>         move a bookmark to a folder, by ID or title
+  text [--html] <id>         print the article of a bookmark as markdown
+  folders                    list the folders
+
+Flags:
+  -o, --output format  print the results as table (default), json or plain,
+                       plain is tab separated values without a header
+  --timeout duration   give up on requests and commands after this long, e.g. 30s
+                       (requests time out after 10s by default)
+
+Command flags go before the arguments, e.g. gopaper list -o json --folder archive.
+`
+
+// timeout is set with the global --timeout flag
+var timeout time.Duration
+
+// commandContext returns the context for a command, canceled on interrupt or after --timeout
+func commandContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	if timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+func runCommand(args []string) error {
+	if isClientCommand(args[0]) {
+		return runClientCommand(os.Stdout, args)
+	}
+	switch args[0] {
+	case "login":
+		return login()
+	case "logout":
+		return logout()
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return nil
+	}
+	return fmt.Errorf("unknown command %q\n\n%s", args[0], usage)
+}
+
+// login asks for the Instapaper username and password and stores the access token.
+// The password itself is never stored.
+func login() error {
+	reader := bufio.NewReader(os.Stdin)
+	username := os.Getenv("IP_USER")
+	if username != "" {
+		fmt.Printf("Username [%s]: ", username)
+	} else {
+		fmt.Print("Username: ")
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil && line == "" {
+		return fmt.Errorf("failed to read username: %w", err)
+	}
+	if line = strings.TrimSpace(line); line != "" {
+		username = line
+	}
+
+	fmt.Print("Password: ")
+	var password string
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		p, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		if err != nil {
+			return fmt.Errorf("failed to read password: %w", err)
+		}
+		password = string(p)
+	} else {
+		// e.g. piped input
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("failed to read password: %w", err)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+	token, err := instapaper.Login(ctx, username, password, clientOptions()...)
+	if err != nil {
+		return err
+	}
+	if err := tokenstore.Save(token); err != nil {
+		return err
+	}
+	c, err := cache.Open()
+	if err != nil {
+		return err
+	}
+	client, err := instapaper.NewClient(clientOptions(instapaper.WithToken(token))...)
+	if err != nil {
+		return err
+	}
+	if _, err := forgetOtherAccount(ctx, client, c); err != nil {
+		return err
+	}
+	path, err := tokenstore.Path()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Logged in as %s, token saved to %s\n", username, path)
+	return nil
+}
+
+// logout deletes the token and the cached bookmarks of the account
+func logout() error {
+	if err := tokenstore.Delete(); err != nil {
+		return err
+	}
+	c, err := cache.Open()
+	if err != nil {
+		return err
+	}
+	if err := c.Clear(); err != nil {
+		return err
+	}
+	fmt.Println("Logged out")
+	return nil
+}
+
+// loadToken returns the stored access token. Setups that still have IP_USER and
+// IP_PASSWORD in the environment are logged in once and the token is stored.
+func loadToken(ctx context.Context) (instapaper.Token, error) {
+	token, err := tokenstore.Load()
+	if !errors.Is(err, tokenstore.ErrNotFound) {
+		return token, err
+	}
+	username, password := os.Getenv("IP_USER"), os.Getenv("IP_PASSWORD")
+	if username == "" || password == "" {
+		return token, err
+	}
+	token, err = instapaper.Login(ctx, username, password, clientOptions()...)
+	if err != nil {
+		return instapaper.Token{}, err
+	}
+	return token, tokenstore.Save(token)
+}
+
+// forgetOtherAccount clears the cache once it is confirmed to have the data of another account
+// than the one the client is logged in to, so the new account does not see the bookmarks or
+// send the queued read progress of the previous one. It reports whether the cache was cleared.
+func forgetOtherAccount(ctx context.Context, client instapaper.Client, c *cache.Cache) (bool, error) {
+	user, err := client.VerifyCredentials(ctx)
+	if err != nil {
+		// the account is not known, the cache is kept
+		return false, nil
+	}
+	cached, err := c.User()
+	if err != nil || cached.UserID == user.UserID {
+		_ = c.SaveUser(user)
+		return false, nil
+	}
+	if err := c.ClearAll(); err != nil {
+		return true, err
+	}
+	// best effort, the user is saved again when the TUI starts
+	_ = c.SaveUser(user)
+	return true, nil
+}
+
+// clientOptions configures the API client from the environment and the flags, see README.md
+func clientOptions(opts ...instapaper.Option) []instapaper.Option {
+	defaults := []instapaper.Option{
+		instapaper.WithEnv(),
+		instapaper.WithUserAgent("gopaper"),
+	}
+	if timeout > 0 {
+		defaults = append(defaults, instapaper.WithTimeout(timeout))
+	}
+	return append(defaults, opts...)
+}
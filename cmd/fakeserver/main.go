@@ -0,0 +1,25 @@
+// fakeserver serves a fake Instapaper API seeded with fixtures, for offline development
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/ieroNo47/gopaper/internal/instapaper/fakeserver"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "address to listen on")
+	flag.Parse()
+
+	fmt.Printf("Serving a fake Instapaper API on http://%s\n\n", *addr)
+	fmt.Println("Point gopaper at it with:")
+	fmt.Printf("  IP_API=http://%s%s\n", *addr, fakeserver.BasePath)
+	fmt.Printf("  IP_OAUTH_CONSUMER_ID=%s\n", fakeserver.ConsumerKey)
+	fmt.Printf("  IP_OAUTH_CONSUMER_SECRET=%s\n\n", fakeserver.ConsumerSecret)
+	fmt.Printf("and log in as %q with the password %q.\n", fakeserver.Username, fakeserver.Password)
+
+	log.Fatal(http.ListenAndServe(*addr, fakeserver.New()))
+}
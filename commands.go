@@ -0,0 +1,402 @@
+// headless commands for scripting
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	htmltomarkdown "github.com/JohannesKaufmann/html-to-markdown/v2"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/ieroNo47/gopaper/internal/cache"
+	"github.com/ieroNo47/gopaper/internal/instapaper"
+	"github.com/ieroNo47/gopaper/internal/ledger"
+	"github.com/ieroNo47/gopaper/internal/tokenstore"
+	"golang.org/x/term"
+)
+
+// outputFormat is how commands print their results, set with --output
+type outputFormat string
+
+const (
+	formatTable outputFormat = "table"
+	formatJSON  outputFormat = "json"
+	// formatPlain prints tab separated values without a header, for cut and awk
+	formatPlain outputFormat = "plain"
+)
+
+func (f *outputFormat) String() string {
+	return string(*f)
+}
+
+func (f *outputFormat) Set(s string) error {
+	switch format := outputFormat(s); format {
+	case formatTable, formatJSON, formatPlain:
+		*f = format
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q, use table, json or plain", s)
+}
+
+// output is set with the global --output flag and the --output flag of every command
+var output = formatTable
+
+// maxTitleWidth truncates titles in tables
+const maxTitleWidth = 60
+
+// isClientCommand reports whether a command calls the API
+func isClientCommand(name string) bool {
+	switch name {
+	case "list", "add", "send", "newsletters", "import", "archive", "unarchive", "star", "unstar", "move", "text", "folders":
+		return true
+	}
+	return false
+}
+
+// runClientCommand runs a command that calls the API and prints the result to w
+func runClientCommand(w io.Writer, args []string) error {
+	name := args[0]
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Var(&output, "output", "")
+	fs.Var(&output, "o", "")
+	var folder, title, description string
+	var limit int
+	var html, all, dryRun bool
+	var format string
+	switch name {
+	case "list":
+		fs.StringVar(&folder, "folder", instapaper.FolderUnread, "")
+		fs.IntVar(&limit, "limit", 25, "")
+		fs.BoolVar(&all, "all", false, "")
+	case "add":
+		fs.StringVar(&folder, "folder", "", "")
+		fs.StringVar(&title, "title", "", "")
+		fs.StringVar(&description, "description", "", "")
+	case "send":
+		fs.StringVar(&folder, "folder", "", "")
+		fs.StringVar(&title, "title", "", "")
+	case "newsletters":
+		fs.StringVar(&folder, "folder", "", "")
+	case "import":
+		fs.StringVar(&format, "format", "", "")
+		fs.BoolVar(&dryRun, "dry-run", false, "")
+	case "text":
+		fs.BoolVar(&html, "html", false, "")
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return fmt.Errorf("%s: %w\n\n%s", name, err, usage)
+	}
+	wantArgs := 1
+	switch name {
+	case "list", "folders":
+		wantArgs = 0
+	case "move":
+		wantArgs = 2
+	}
+	switch {
+	case name == "newsletters" && fs.NArg() == 0:
+		return fmt.Errorf("%s takes at least 1 argument\n\n%s", name, usage)
+	case name != "newsletters" && fs.NArg() != wantArgs:
+		return fmt.Errorf("%s takes %d argument(s), got %d\n\n%s", name, wantArgs, fs.NArg(), usage)
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+	client, err := newCommandClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch name {
+	case "list":
+		if limit < 1 || limit > instapaper.MaxListLimit {
+			return fmt.Errorf("--limit must be between 1 and %d, got %d", instapaper.MaxListLimit, limit)
+		}
+		folderID, err := resolveFolder(ctx, client, folder)
+		if err != nil {
+			return err
+		}
+		list := client.ListBookmarks
+		if all {
+			list = client.ListAllBookmarks
+			limit = instapaper.MaxListLimit
+		}
+		resp, err := list(ctx, instapaper.ListOptions{Limit: limit, FolderID: folderID})
+		if err != nil {
+			return err
+		}
+		return printBookmarks(w, resp.Bookmarks)
+	case "folders":
+		folders, err := client.ListFolders(ctx)
+		if err != nil {
+			return err
+		}
+		return printFolders(w, folders)
+	case "add", "send":
+		params := instapaper.AddBookmarkParams{URL: fs.Arg(0), Title: title, Description: description}
+		if name == "send" {
+			if params, err = fileBookmark(fs.Arg(0)); err != nil {
+				return err
+			}
+			if title != "" {
+				params.Title = title
+			}
+		}
+		if folder != "" {
+			if params.FolderID, err = resolveUserFolder(ctx, client, folder); err != nil {
+				return err
+			}
+		}
+		bookmark, err := client.AddBookmark(ctx, params)
+		if err != nil {
+			return err
+		}
+		return printBookmarks(w, []instapaper.Bookmark{bookmark})
+	case "newsletters":
+		newsletters := []newsletter{}
+		for _, path := range fs.Args() {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			read, skipped := readNewsletters(data)
+			for _, err := range skipped {
+				fmt.Fprintf(os.Stderr, "skipped %s: %v\n", path, err)
+			}
+			newsletters = append(newsletters, read...)
+		}
+		var folderID int64
+		if folder != "" {
+			if folderID, err = resolveUserFolder(ctx, client, folder); err != nil {
+				return err
+			}
+		}
+		l, err := openLedger(ctx, client)
+		if err != nil {
+			return err
+		}
+		added, skipped, importErr := importNewsletters(ctx, client, l, newsletters, folderID)
+		if skipped > 0 {
+			fmt.Fprintf(os.Stderr, "skipped %d newsletter(s) imported before\n", skipped)
+		}
+		if err := printBookmarks(w, added); err != nil {
+			return err
+		}
+		return importErr
+	case "import":
+		data, err := os.ReadFile(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		if format == "" {
+			if format, err = detectFormat(fs.Arg(0), data); err != nil {
+				return err
+			}
+		}
+		items, err := parseExport(format, data)
+		if err != nil {
+			return err
+		}
+		l, err := openLedger(ctx, client)
+		if err != nil {
+			return err
+		}
+		im, err := newImporter(ctx, client, l, dryRun)
+		if err != nil {
+			return err
+		}
+		progress := io.Discard
+		if term.IsTerminal(int(os.Stderr.Fd())) {
+			progress = os.Stderr
+		}
+		results, importErr := im.run(items, progress)
+		if err := printImportResults(w, results); err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, importSummary(results, im.newFolders, dryRun))
+		return importErr
+	}
+
+	bookmarkID, err := parseBookmarkID(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var bookmark instapaper.Bookmark
+	switch name {
+	case "text":
+		text, err := client.GetBookmarkText(ctx, bookmarkID)
+		if err != nil {
+			return err
+		}
+		return printText(w, bookmarkID, text, html)
+	case "archive":
+		bookmark, err = client.ArchiveBookmark(ctx, bookmarkID)
+	case "unarchive":
+		bookmark, err = client.UnarchiveBookmark(ctx, bookmarkID)
+	case "star":
+		bookmark, err = client.StarBookmark(ctx, bookmarkID)
+	case "unstar":
+		bookmark, err = client.UnstarBookmark(ctx, bookmarkID)
+	case "move":
+		folderID, ferr := resolveUserFolder(ctx, client, fs.Arg(1))
+		if ferr != nil {
+			return ferr
+		}
+		bookmark, err = client.MoveBookmark(ctx, bookmarkID, folderID)
+	}
+	if err != nil {
+		return err
+	}
+	return printBookmarks(w, []instapaper.Bookmark{bookmark})
+}
+
+// newCommandClient returns a client with the stored access token
+func newCommandClient(ctx context.Context) (instapaper.Client, error) {
+	token, err := loadToken(ctx)
+	if errors.Is(err, tokenstore.ErrNotFound) {
+		return instapaper.Client{}, errors.New("not logged in, run gopaper login first")
+	}
+	if err != nil {
+		return instapaper.Client{}, err
+	}
+	return instapaper.NewClient(clientOptions(instapaper.WithToken(token))...)
+}
+
+// openLedger returns the import ledger of the account the client is logged in to,
+// the cached account is used when the credentials cannot be verified
+func openLedger(ctx context.Context, client instapaper.Client) (*ledger.Ledger, error) {
+	user, err := client.VerifyCredentials(ctx)
+	if err != nil {
+		c, cacheErr := cache.Open()
+		if cacheErr != nil {
+			return nil, err
+		}
+		cached, cacheErr := c.User()
+		if cacheErr != nil || cached.UserID == 0 {
+			return nil, err
+		}
+		user = cached
+	}
+	return ledger.Open(user.UserID)
+}
+
+// resolveFolder returns the bookmarks/list folder ID for unread, starred, archive,
+// a folder ID or the title of a user-created folder
+func resolveFolder(ctx context.Context, client instapaper.Client, folder string) (string, error) {
+	switch strings.ToLower(folder) {
+	case instapaper.FolderUnread, "home", "":
+		return instapaper.FolderUnread, nil
+	case instapaper.FolderStarred, instapaper.FolderArchive:
+		return strings.ToLower(folder), nil
+	}
+	folderID, err := resolveUserFolder(ctx, client, folder)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(folderID, 10), nil
+}
+
+// resolveUserFolder returns the ID of a user-created folder given its ID, title or slug
+func resolveUserFolder(ctx context.Context, client instapaper.Client, folder string) (int64, error) {
+	if folderID, err := strconv.ParseInt(folder, 10, 64); err == nil {
+		return folderID, nil
+	}
+	folders, err := client.ListFolders(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, f := range folders {
+		if strings.EqualFold(f.Title, folder) || f.Slug == folder {
+			return f.FolderID, nil
+		}
+	}
+	return 0, fmt.Errorf("no folder %q, see gopaper folders", folder)
+}
+
+// misc helper functions
+
+func parseBookmarkID(s string) (int64, error) {
+	bookmarkID, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || bookmarkID <= 0 {
+		return 0, fmt.Errorf("invalid bookmark ID %q", s)
+	}
+	return bookmarkID, nil
+}
+
+func printBookmarks(w io.Writer, bookmarks []instapaper.Bookmark) error {
+	switch output {
+	case formatJSON:
+		return printJSON(w, bookmarks)
+	case formatPlain:
+		for _, b := range bookmarks {
+			fmt.Fprintf(w, "%d\t%s\t%s\n", b.BookmarkID, b.Title, b.URL)
+		}
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTITLE\tURL\tPROGRESS\tSTARRED\tTAGS")
+	for _, b := range bookmarks {
+		tags := []string{}
+		for _, t := range b.Tags {
+			tags = append(tags, t.Name)
+		}
+		starred := ""
+		if b.Starred == "1" {
+			starred = "★"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%.0f%%\t%s\t%s\n",
+			b.BookmarkID, ansi.Truncate(b.Title, maxTitleWidth, "…"), b.URL, b.Progress*100, starred, strings.Join(tags, ","))
+	}
+	return tw.Flush()
+}
+
+func printFolders(w io.Writer, folders []instapaper.Folder) error {
+	switch output {
+	case formatJSON:
+		return printJSON(w, folders)
+	case formatPlain:
+		for _, f := range folders {
+			fmt.Fprintf(w, "%d\t%s\n", f.FolderID, f.Title)
+		}
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTITLE\tSLUG")
+	for _, f := range folders {
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", f.FolderID, f.Title, f.Slug)
+	}
+	return tw.Flush()
+}
+
+// printText prints the article as markdown, or the HTML from the API with --html
+func printText(w io.Writer, bookmarkID int64, text string, html bool) error {
+	if output == formatJSON {
+		return printJSON(w, struct {
+			BookmarkID int64  `json:"bookmark_id"`
+			HTML       string `json:"html"`
+		}{bookmarkID, text})
+	}
+	if !html {
+		markdown, err := htmltomarkdown.ConvertString(text)
+		if err != nil {
+			return fmt.Errorf("failed to convert article to markdown: %w", err)
+		}
+		text = markdown
+	}
+	_, err := fmt.Fprintln(w, strings.TrimRight(text, "\n"))
+	return err
+}
+
+func printJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
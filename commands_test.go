@@ -0,0 +1,536 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ieroNo47/gopaper/internal/cache"
+	"github.com/ieroNo47/gopaper/internal/instapaper"
+	"github.com/ieroNo47/gopaper/internal/instapaper/fakeserver"
+	"github.com/ieroNo47/gopaper/internal/ledger"
+	"github.com/ieroNo47/gopaper/internal/tokenstore"
+)
+
+// runTestCommand runs a command and returns what it printed
+func runTestCommand(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	t.Cleanup(func() { output = formatTable })
+	var buf bytes.Buffer
+	err := runClientCommand(&buf, args)
+	return buf.String(), err
+}
+
+func TestListCommand(t *testing.T) {
+	srv, _ := testEnv(t)
+	if err := tokenstore.Save(srv.IssueToken()); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runTestCommand(t, "list")
+	if err != nil {
+		t.Fatalf("list error = %v", err)
+	}
+	if !strings.HasPrefix(out, "ID") || !strings.Contains(out, "The Go Programming Language Specification") {
+		t.Errorf("list printed %q, want a table of the unread bookmarks", out)
+	}
+
+	out, err = runTestCommand(t, "list", "-o", "plain", "--folder", "tech")
+	if err != nil {
+		t.Fatalf("list --folder tech error = %v", err)
+	}
+	if want := strconv.FormatInt(fakeserver.BookmarkNoText, 10) + "\t"; !strings.HasPrefix(out, want) || strings.Count(out, "\n") != 1 {
+		t.Errorf("list --folder tech printed %q, want only bookmark %d", out, fakeserver.BookmarkNoText)
+	}
+
+	out, err = runTestCommand(t, "list", "--output", "json", "--folder", "archive")
+	if err != nil {
+		t.Fatalf("list --folder archive error = %v", err)
+	}
+	var bookmarks []instapaper.Bookmark
+	if err := json.Unmarshal([]byte(out), &bookmarks); err != nil {
+		t.Fatalf("list -o json printed invalid JSON: %v", err)
+	}
+	if len(bookmarks) != 1 || bookmarks[0].BookmarkID != fakeserver.BookmarkArchived {
+		t.Errorf("list --folder archive = %v, want bookmark %d", bookmarkIDs(bookmarks), fakeserver.BookmarkArchived)
+	}
+
+	out, err = runTestCommand(t, "list", "-o", "plain", "--limit", "1", "--all")
+	if err != nil {
+		t.Fatalf("list --all error = %v", err)
+	}
+	if n := strings.Count(out, "\n"); n != 3 {
+		t.Errorf("list --limit 1 --all printed %d bookmarks, want all 3", n)
+	}
+}
+
+func TestBookmarkCommands(t *testing.T) {
+	srv, _ := testEnv(t)
+	if err := tokenstore.Save(srv.IssueToken()); err != nil {
+		t.Fatal(err)
+	}
+	id := strconv.FormatInt(fakeserver.BookmarkOAuth, 10)
+
+	out, err := runTestCommand(t, "add", "-o", "json", "--title", "New", "--folder", "Recipes", "https://example.com/new")
+	if err != nil {
+		t.Fatalf("add error = %v", err)
+	}
+	var added []instapaper.Bookmark
+	if err := json.Unmarshal([]byte(out), &added); err != nil || len(added) != 1 {
+		t.Fatalf("add printed %q, want the bookmark as JSON", out)
+	}
+	if _, folder, _ := srv.Bookmark(added[0].BookmarkID); folder != strconv.FormatInt(fakeserver.FolderRecipes, 10) {
+		t.Errorf("added bookmark is in folder %q, want %d", folder, fakeserver.FolderRecipes)
+	}
+
+	if _, err := runTestCommand(t, "star", id); err != nil {
+		t.Errorf("star error = %v", err)
+	}
+	if b, _, _ := srv.Bookmark(fakeserver.BookmarkOAuth); b.Starred != "1" {
+		t.Error("star did not star the bookmark")
+	}
+	if _, err := runTestCommand(t, "move", id, "tech"); err != nil {
+		t.Errorf("move error = %v", err)
+	}
+	if _, folder, _ := srv.Bookmark(fakeserver.BookmarkOAuth); folder != strconv.FormatInt(fakeserver.FolderTech, 10) {
+		t.Errorf("moved bookmark is in folder %q, want %d", folder, fakeserver.FolderTech)
+	}
+	if _, err := runTestCommand(t, "archive", id); err != nil {
+		t.Errorf("archive error = %v", err)
+	}
+	if _, folder, _ := srv.Bookmark(fakeserver.BookmarkOAuth); folder != instapaper.FolderArchive {
+		t.Errorf("archived bookmark is in folder %q", folder)
+	}
+
+	if _, err := runTestCommand(t, "move", id, "nowhere"); err == nil {
+		t.Error("move to an unknown folder succeeded")
+	}
+	if _, err := runTestCommand(t, "star", "abc"); err == nil {
+		t.Error("star with an invalid ID succeeded")
+	}
+	if _, err := runTestCommand(t, "star"); err == nil {
+		t.Error("star without an ID succeeded")
+	}
+}
+
+func TestSendCommand(t *testing.T) {
+	srv, _ := testEnv(t)
+	if err := tokenstore.Save(srv.IssueToken()); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "notes.md")
+	if err := os.WriteFile(path, []byte("```sh\n# not the title\n```\n\n# Meeting notes\n\n- *one*\n- two\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runTestCommand(t, "send", "-o", "json", "--folder", "tech", path)
+	if err != nil {
+		t.Fatalf("send error = %v", err)
+	}
+	var sent []instapaper.Bookmark
+	if err := json.Unmarshal([]byte(out), &sent); err != nil || len(sent) != 1 {
+		t.Fatalf("send printed %q, want the bookmark as JSON", out)
+	}
+	b, folder, _ := srv.Bookmark(sent[0].BookmarkID)
+	if b.Title != "Meeting notes" || b.PrivateSource != privateSource || folder != strconv.FormatInt(fakeserver.FolderTech, 10) {
+		t.Errorf("sent bookmark = %q from %q in folder %q, want a private bookmark titled by the heading in Tech", b.Title, b.PrivateSource, folder)
+	}
+	out, err = runTestCommand(t, "text", "-o", "plain", "--html", strconv.FormatInt(b.BookmarkID, 10))
+	if err != nil || !strings.Contains(out, "<li><em>one</em></li>") {
+		t.Errorf("text of the sent bookmark = %q, %v, want the Markdown as HTML", out, err)
+	}
+
+	if _, err := runTestCommand(t, "send", filepath.Join(t.TempDir(), "missing.md")); err == nil {
+		t.Error("send of a missing file succeeded")
+	}
+	for name, data := range map[string]string{"photo.png": "\x89PNG\r\n", "latin1.txt": "caf\xe9\n"} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := runTestCommand(t, "send", path); err == nil {
+			t.Errorf("send of %s succeeded, want an error", name)
+		}
+	}
+}
+
+const testMbox = `From news@example.com Mon Jan  6 08:00:00 2025
+From: "Weekly, Go" <news@example.com>
+Subject: =?utf-8?q?Go_Weekly_=E2=80=93_Issue_1?=
+Message-ID: <issue-1@example.com>
+MIME-Version: 1.0
+Content-Type: multipart/alternative; boundary="b1"
+
+--b1
+Content-Type: text/plain; charset=utf-8
+
+The plain text version
+--b1
+Content-Type: text/html; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
+<html><head><style>p { color: red }</style><script>track()</script></head>=
+<body><p onclick=3D"track()">Generics are <b>here</b></p>
+>From the editor
+</body></html>
+--b1
+Content-Type: text/html
+Content-Disposition: attachment; filename="ad.html"
+
+<p>Attachment</p>
+--b1--
+
+From recipes@example.com Tue Jan  7 08:00:00 2025
+From: recipes@example.com
+Subject: Bread
+Message-ID: <bread@example.com>
+Content-Type: text/plain; charset=iso-8859-1
+Content-Transfer-Encoding: base64
+
+Q3LobWUgYnL7bOllCkZyb20gc2NyYXRjaA==
+
+From photos@example.com Wed Jan  8 08:00:00 2025
+From: photos@example.com
+Subject: Photo
+Content-Type: image/png
+
+not a newsletter
+
+From cafe@example.com Thu Jan  9 08:00:00 2025
+From: =?windows-1252?q?Caf=E9_News?= <cafe@example.com>
+Subject: =?windows-1252?q?Caf=E9_=96_Menu?=
+Message-ID: <menu@example.com>
+Content-Type: text/plain; charset=windows-1252
+Content-Transfer-Encoding: quoted-printable
+
+Caf=E9 =96 today
+`
+
+func TestNewslettersCommand(t *testing.T) {
+	srv, _ := testEnv(t)
+	if err := tokenstore.Save(srv.IssueToken()); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	mbox := filepath.Join(dir, "newsletters.mbox")
+	if err := os.WriteFile(mbox, []byte(testMbox), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runTestCommand(t, "newsletters", "-o", "json", "--folder", "tech", mbox)
+	if err != nil {
+		t.Fatalf("newsletters error = %v", err)
+	}
+	var added []instapaper.Bookmark
+	// the photo is skipped
+	if err := json.Unmarshal([]byte(out), &added); err != nil || len(added) != 3 {
+		t.Fatalf("newsletters printed %q, want the 3 bookmarks as JSON", out)
+	}
+	issue, folder, _ := srv.Bookmark(added[0].BookmarkID)
+	if issue.Title != "Go Weekly – Issue 1" || issue.PrivateSource != newsletterSource || folder != strconv.FormatInt(fakeserver.FolderTech, 10) {
+		t.Errorf("first newsletter = %q from %q in folder %q, want a private bookmark titled by the subject in Tech", issue.Title, issue.PrivateSource, folder)
+	}
+	if tags := instapaper.TagNames(issue.Tags); !slices.Equal(tags, []string{"Weekly Go"}) {
+		t.Errorf("first newsletter tags = %v, want the sender", tags)
+	}
+	html, err := runTestCommand(t, "text", "-o", "plain", "--html", strconv.FormatInt(issue.BookmarkID, 10))
+	if err != nil || !strings.Contains(html, "Generics are <b>here</b>") || !strings.Contains(html, "\nFrom the editor") {
+		t.Errorf("text of the first newsletter = %q, %v, want the HTML part", html, err)
+	}
+	for _, removed := range []string{"track()", "color", "Attachment", "plain text"} {
+		if strings.Contains(html, removed) {
+			t.Errorf("text of the first newsletter contains %q", removed)
+		}
+	}
+	bread, _, _ := srv.Bookmark(added[1].BookmarkID)
+	if tags := instapaper.TagNames(bread.Tags); bread.Title != "Bread" || !slices.Equal(tags, []string{"recipes@example.com"}) {
+		t.Errorf("second newsletter = %q tagged %v, want Bread tagged with the address", bread.Title, tags)
+	}
+	text, err := runTestCommand(t, "text", "-o", "plain", "--html", strconv.FormatInt(bread.BookmarkID, 10))
+	if err != nil || !strings.Contains(text, "Crème brûlée<br>\nFrom scratch") {
+		t.Errorf("text of the second newsletter = %q, %v, want the decoded text", text, err)
+	}
+	menu, _, _ := srv.Bookmark(added[2].BookmarkID)
+	if tags := instapaper.TagNames(menu.Tags); menu.Title != "Café – Menu" || !slices.Equal(tags, []string{"Café News"}) {
+		t.Errorf("last newsletter = %q tagged %v, want the Windows-1252 subject and sender decoded", menu.Title, tags)
+	}
+	text, err = runTestCommand(t, "text", "-o", "plain", "--html", strconv.FormatInt(menu.BookmarkID, 10))
+	if err != nil || !strings.Contains(text, "Café – today") {
+		t.Errorf("text of the last newsletter = %q, %v, want the decoded text", text, err)
+	}
+
+	// the same email saved as .eml is not imported again
+	eml := filepath.Join(dir, "issue-1.eml")
+	if err := os.WriteFile(eml, []byte(testMbox[strings.Index(testMbox, "\n")+1:]), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// clearing the cache, e.g. by logging out, keeps the record of the imports
+	c, err := cache.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	out, err = runTestCommand(t, "newsletters", "-o", "plain", mbox, eml)
+	if err != nil || out != "" {
+		t.Errorf("newsletters again printed %q, %v, want nothing imported", out, err)
+	}
+	if _, err := runTestCommand(t, "newsletters"); err == nil {
+		t.Error("newsletters without a file succeeded")
+	}
+}
+
+const testNetscapeExport = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+    <DT><H3 PERSONAL_TOOLBAR_FOLDER="true">Bookmarks bar</H3>
+    <DL><p>
+        <DT><A HREF="https://example.com/bar" TAGS="news">On the bar</A>
+        <DT><H3>recipes</H3>
+        <DL><p>
+            <DT><A HREF="https://example.com/bread">Bread &amp; butter</A>
+            <DD>A classic
+        </DL><p>
+    </DL><p>
+    <DT><H3>Reading</H3>
+    <DL><p>
+        <DT><A HREF="https://go.dev/ref/spec">Spec</A>
+        <DT><A HREF="javascript:void(0)">Bookmarklet</A>
+        <DT><A HREF="https://example.com/novel">Novel</A>
+    </DL><p>
+</DL><p>
+`
+
+// runImport runs the import command and returns the actions of the JSON report
+func runImport(t *testing.T, args ...string) ([]importResult, []string) {
+	t.Helper()
+	out, err := runTestCommand(t, append([]string{"import", "-o", "json"}, args...)...)
+	if err != nil {
+		t.Fatalf("import %v error = %v", args, err)
+	}
+	var results []importResult
+	if err := json.Unmarshal([]byte(out), &results); err != nil {
+		t.Fatalf("import printed %q, want the report as JSON", out)
+	}
+	actions := []string{}
+	for _, r := range results {
+		actions = append(actions, r.Action)
+	}
+	return results, actions
+}
+
+func TestImportCommand(t *testing.T) {
+	srv, _ := testEnv(t)
+	if err := tokenstore.Save(srv.IssueToken()); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	bookmarks := filepath.Join(dir, "bookmarks.html")
+	if err := os.WriteFile(bookmarks, []byte(testNetscapeExport), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{importAdd, importAdd, importExists, importInvalid, importAdd}
+
+	results, actions := runImport(t, "--dry-run", bookmarks)
+	if !slices.Equal(actions, want) || results[0].BookmarkID != 0 {
+		t.Errorf("dry run actions = %v, want %v without bookmarks", actions, want)
+	}
+	if folders, _ := runTestCommand(t, "folders", "-o", "plain"); strings.Contains(folders, "Reading") {
+		t.Error("dry run created the Reading folder")
+	}
+
+	results, actions = runImport(t, bookmarks)
+	if !slices.Equal(actions, want) {
+		t.Errorf("import actions = %v, want %v", actions, want)
+	}
+	bar, folder, _ := srv.Bookmark(results[0].BookmarkID)
+	if tags := instapaper.TagNames(bar.Tags); folder != instapaper.FolderUnread || !slices.Equal(tags, []string{"news"}) {
+		t.Errorf("bookmark on the bar is in folder %q tagged %v, want Home tagged news", folder, tags)
+	}
+	bread, folder, _ := srv.Bookmark(results[1].BookmarkID)
+	if bread.Title != "Bread & butter" || bread.Description != "A classic" || folder != strconv.FormatInt(fakeserver.FolderRecipes, 10) {
+		t.Errorf("bread = %q %q in folder %q, want it in Recipes", bread.Title, bread.Description, folder)
+	}
+	if folders, _ := runTestCommand(t, "folders", "-o", "plain"); !strings.Contains(folders, "Reading") {
+		t.Error("import did not create the Reading folder")
+	}
+
+	// archived Pocket bookmarks go to the archive, imported URLs are skipped
+	pocket := filepath.Join(dir, "ril_export.html")
+	export := `<!DOCTYPE html><html><head><title>Pocket Export</title></head><body>
+<h1>Unread</h1><ul><li><a href="https://example.com/bar" time_added="1700000000" tags="">On the bar</a></li></ul>
+<h1>Read Archive</h1><ul><li><a href="https://example.com/old" time_added="1600000000" tags="history,long read">Old</a></li></ul>
+</body></html>`
+	if err := os.WriteFile(pocket, []byte(export), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	results, actions = runImport(t, pocket)
+	if want := []string{importDone, importAdd}; !slices.Equal(actions, want) {
+		t.Errorf("Pocket import actions = %v, want %v", actions, want)
+	}
+	old, folder, _ := srv.Bookmark(results[1].BookmarkID)
+	if tags := instapaper.TagNames(old.Tags); folder != instapaper.FolderArchive || !slices.Equal(tags, []string{"history", "long read"}) {
+		t.Errorf("archived Pocket bookmark is in folder %q tagged %v", folder, tags)
+	}
+
+	// a bookmark added by an interrupted import gets its state on the next run
+	ctx := context.Background()
+	client, err := newCommandClient(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	added, err := client.AddBookmark(ctx, instapaper.AddBookmarkParams{URL: "https://example.com/interrupted"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := client.VerifyCredentials(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := ledger.Open(user.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Save(importKey(added.URL), added.BookmarkID); err != nil {
+		t.Fatal(err)
+	}
+	wallabag := filepath.Join(dir, "wallabag.json")
+	export = `[{"title": "Interrupted", "url": "https://example.com/interrupted", "is_archived": 1, "is_starred": 1, "tags": []}]`
+	if err := os.WriteFile(wallabag, []byte(export), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, actions = runImport(t, wallabag); !slices.Equal(actions, []string{importUpdate}) {
+		t.Errorf("resumed import actions = %v, want update", actions)
+	}
+	if b, folder, _ := srv.Bookmark(added.BookmarkID); b.Starred != "1" || folder != instapaper.FolderArchive {
+		t.Errorf("resumed bookmark starred %q in folder %q, want it starred and archived", b.Starred, folder)
+	}
+	if _, actions = runImport(t, wallabag); !slices.Equal(actions, []string{importDone}) {
+		t.Errorf("import again actions = %v, want done", actions)
+	}
+
+	// a URL imported into another account is imported into this one
+	other, err := ledger.Open(user.UserID + 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Save(importKey("https://example.com/other-account"), 1); err != nil {
+		t.Fatal(err)
+	}
+	export = `[{"title": "Other account", "url": "https://example.com/other-account", "is_archived": 0, "is_starred": 0, "tags": []}]`
+	if err := os.WriteFile(wallabag, []byte(export), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, actions = runImport(t, wallabag); !slices.Equal(actions, []string{importAdd}) {
+		t.Errorf("import of a URL imported into another account actions = %v, want add", actions)
+	}
+
+	if _, err := runTestCommand(t, "import", filepath.Join(dir, "unknown.txt")); err == nil {
+		t.Error("import of a missing file succeeded")
+	}
+}
+
+func TestParseExport(t *testing.T) {
+	tests := []struct {
+		name, path, export string
+		want               []importItem
+	}{
+		{
+			name: "instapaper csv",
+			path: "instapaper-export.csv",
+			export: "\ufeffURL,Title,Selection,Folder,Timestamp,Tags\n" +
+				"https://example.com/a,A,,Unread,1,[]\n" +
+				"https://example.com/b,B,quote,Archive,1,\"[go, tui]\"\n" +
+				"https://example.com/c,C,,Starred,1,\n" +
+				"https://example.com/d,D,,Recipes,1,\n",
+			want: []importItem{
+				{URL: "https://example.com/a", Title: "A", Tags: []string{}},
+				{URL: "https://example.com/b", Title: "B", Description: "quote", Tags: []string{"go", "tui"}, Archived: true},
+				{URL: "https://example.com/c", Title: "C", Tags: []string{}, Starred: true},
+				{URL: "https://example.com/d", Title: "D", Tags: []string{}, Folder: "Recipes"},
+			},
+		},
+		{
+			name:   "pocket csv",
+			path:   "part_000000.csv",
+			export: "title,url,time_added,tags,status\nA,https://example.com/a,1,go|tui,archive\n",
+			want:   []importItem{{URL: "https://example.com/a", Title: "A", Tags: []string{"go", "tui"}, Archived: true}},
+		},
+		{
+			name:   "wallabag",
+			path:   "wallabag.json",
+			export: `[{"title": "A", "url": "https://example.com/a", "is_archived": 0, "is_starred": true, "tags": ["go"]}]`,
+			want:   []importItem{{URL: "https://example.com/a", Title: "A", Tags: []string{"go"}, Starred: true}},
+		},
+		{
+			name:   "omnivore",
+			path:   "metadata_0_to_1.json",
+			export: `[{"title": "A", "url": "https://example.com/a", "description": "About A", "labels": ["go"], "state": "Archived"}]`,
+			want:   []importItem{{URL: "https://example.com/a", Title: "A", Description: "About A", Tags: []string{"go"}, Archived: true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := detectFormat(tt.path, []byte(tt.export))
+			if err != nil {
+				t.Fatalf("detectFormat() error = %v", err)
+			}
+			got, err := parseExport(format, []byte(tt.export))
+			if err != nil {
+				t.Fatalf("parseExport(%s) error = %v", format, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseExport(%s) = %+v, want %+v", format, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := detectFormat("export.json", []byte(`[{"url": "https://example.com"}]`)); err == nil {
+		t.Error("detectFormat() of an unknown JSON export succeeded")
+	}
+	if _, err := parseExport(formatCSV, []byte("title\nA\n")); err == nil {
+		t.Error("parseExport() of a CSV file without URLs succeeded")
+	}
+}
+
+func TestTextCommand(t *testing.T) {
+	srv, _ := testEnv(t)
+	if err := tokenstore.Save(srv.IssueToken()); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runTestCommand(t, "text", strconv.FormatInt(fakeserver.BookmarkSpec, 10))
+	if err != nil {
+		t.Fatalf("text error = %v", err)
+	}
+	if !strings.Contains(out, "general-purpose language") || strings.Contains(out, "<p>") {
+		t.Errorf("text printed %q, want the article as markdown", out)
+	}
+	if _, err := runTestCommand(t, "text", strconv.FormatInt(fakeserver.BookmarkNoText, 10)); err == nil {
+		t.Error("text of a bookmark without text succeeded")
+	}
+}
+
+func TestCommandNotLoggedIn(t *testing.T) {
+	testEnv(t)
+	if _, err := runTestCommand(t, "folders"); err == nil || !strings.Contains(err.Error(), "gopaper login") {
+		t.Errorf("folders without a token error = %v, want a hint to log in", err)
+	}
+}
+
+func bookmarkIDs(bookmarks []instapaper.Bookmark) []int64 {
+	ids := []int64{}
+	for _, b := range bookmarks {
+		ids = append(ids, b.BookmarkID)
+	}
+	return ids
+}
@@ -0,0 +1,375 @@
+// highlights view
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ieroNo47/gopaper/internal/cache"
+	"github.com/ieroNo47/gopaper/internal/instapaper"
+	"golang.org/x/net/html"
+)
+
+var highlightsStyle = lipgloss.NewStyle().
+	Margin(0).
+	Padding(0).
+	BorderStyle(lipgloss.RoundedBorder()).
+	BorderForeground(lipgloss.Color("5")).
+	MarginBackground(lipgloss.Color("5"))
+
+type highlightKeyMap struct {
+	add    key.Binding
+	delete key.Binding
+	save   key.Binding
+	back   key.Binding
+}
+
+var highlightKeys = highlightKeyMap{
+	add: key.NewBinding(
+		key.WithKeys("n"),
+		key.WithHelp("n", "new highlight"),
+	),
+	delete: key.NewBinding(
+		key.WithKeys("x"),
+		key.WithHelp("x", "delete highlight"),
+	),
+	save: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("enter", "save"),
+	),
+	back: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "back"),
+	),
+}
+
+type highlightItem struct {
+	highlight instapaper.Highlight
+}
+
+func (i highlightItem) Title() string { return i.highlight.Text }
+func (i highlightItem) Description() string {
+	desc := time.Unix(i.highlight.Time, 0).Format(time.DateTime)
+	if note, ok := i.highlight.Note.(string); ok && note != "" {
+		desc = fmt.Sprintf("%s | %s", desc, note)
+	}
+	return desc
+}
+func (i highlightItem) FilterValue() string { return i.highlight.Text }
+
+type highlightsMsg struct {
+	bookmarkID int64
+	highlights []instapaper.Highlight
+}
+
+type highlightCreatedMsg instapaper.Highlight
+
+type highlightDeletedMsg struct {
+	highlightID int64
+}
+
+type highlightsErrMsg struct {
+	err error
+}
+
+// getHighlights fetches the highlights of a bookmark, falling back to the cache when offline
+func getHighlights(ctx context.Context, client instapaper.Client, c *cache.Cache, bookmarkID int64) tea.Cmd {
+	return func() tea.Msg {
+		highlights, err := client.ListHighlights(ctx, bookmarkID)
+		if ctx.Err() != nil {
+			// the view was closed
+			return nil
+		}
+		if err != nil {
+			if cached, cacheErr := c.Highlights(bookmarkID); cacheErr == nil {
+				return highlightsMsg{bookmarkID: bookmarkID, highlights: cached}
+			}
+			return highlightsErrMsg{fmt.Errorf("failed to get highlights: %w", err)}
+		}
+		_ = c.SaveHighlights(bookmarkID, highlights)
+		return highlightsMsg{bookmarkID: bookmarkID, highlights: highlights}
+	}
+}
+
+func createHighlight(ctx context.Context, client instapaper.Client, c *cache.Cache, bookmark instapaper.Bookmark, text string) tea.Cmd {
+	return func() tea.Msg {
+		position, err := highlightPosition(ctx, client, c, bookmark, text)
+		if err != nil {
+			return highlightsErrMsg{fmt.Errorf("failed to create highlight: %w", err)}
+		}
+		highlight, err := client.CreateHighlight(ctx, bookmark.BookmarkID, text, position)
+		if err != nil {
+			return highlightsErrMsg{fmt.Errorf("failed to create highlight: %w", err)}
+		}
+		return highlightCreatedMsg(highlight)
+	}
+}
+
+func deleteHighlight(ctx context.Context, client instapaper.Client, highlightID int64) tea.Cmd {
+	return func() tea.Msg {
+		if err := client.DeleteHighlight(ctx, highlightID); err != nil {
+			return highlightsErrMsg{fmt.Errorf("failed to delete highlight: %w", err)}
+		}
+		return highlightDeletedMsg{highlightID: highlightID}
+	}
+}
+
+// highlightsModel lists the highlights of a single bookmark
+type highlightsModel struct {
+	// ctx is the context the view was opened with, cancel stops loading the highlights
+	ctx      context.Context
+	cancel   context.CancelFunc
+	client   instapaper.Client
+	cache    *cache.Cache
+	bookmark instapaper.Bookmark
+	list     list.Model
+	input    textinput.Model
+	// confirmDelete is the highlight to delete once the user confirms
+	confirmDelete *highlightItem
+	loading       bool
+	err           error
+}
+
+func newHighlightsModel() highlightsModel {
+	m := highlightsModel{
+		list:  list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0),
+		input: textinput.New(),
+	}
+	m.list.SetShowStatusBar(false)
+	m.list.SetShowHelp(false)
+	m.input.Placeholder = "Text to highlight"
+	return m
+}
+
+// open resets the view for a bookmark and starts loading its highlights
+func (m highlightsModel) open(ctx context.Context, client instapaper.Client, c *cache.Cache, bookmark instapaper.Bookmark) (highlightsModel, tea.Cmd) {
+	m.close()
+	m.ctx = ctx
+	loadCtx, cancel := context.WithCancel(ctx)
+	m.cancel = cancel
+	m.client = client
+	m.cache = c
+	m.bookmark = bookmark
+	m.loading = true
+	m.err = nil
+	m.confirmDelete = nil
+	m.input.Blur()
+	m.input.Reset()
+	m.list.Title = bookmark.Title
+	cmd := m.list.SetItems([]list.Item{})
+	return m, tea.Batch(cmd, getHighlights(loadCtx, client, c, bookmark.BookmarkID))
+}
+
+// close stops loading the highlights if they are still in flight
+func (m highlightsModel) close() {
+	if m.cancel != nil {
+		m.cancel()
+	}
+}
+
+// startAdding focuses the input, prefilled with text
+func (m highlightsModel) startAdding(text string) (highlightsModel, tea.Cmd) {
+	m.input.SetValue(text)
+	m.input.CursorEnd()
+	return m, m.input.Focus()
+}
+
+// capturesInput reports whether key presses should go to the view instead of the global bindings
+func (m highlightsModel) capturesInput() bool {
+	return m.input.Focused() || m.list.FilterState() == list.Filtering || m.confirmDelete != nil
+}
+
+func (m *highlightsModel) setSize(width, height int) {
+	m.input.Width = width - 3
+	// leave room for the input and error lines
+	m.list.SetSize(width, height-2)
+}
+
+func (m highlightsModel) Update(msg tea.Msg) (highlightsModel, tea.Cmd) {
+	var cmd tea.Cmd
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if m.input.Focused() {
+			switch {
+			case key.Matches(msg, highlightKeys.save):
+				text := m.input.Value()
+				m.input.Blur()
+				m.input.Reset()
+				if text == "" {
+					return m, nil
+				}
+				return m, createHighlight(m.ctx, m.client, m.cache, m.bookmark, text)
+			case key.Matches(msg, highlightKeys.back):
+				m.input.Blur()
+				m.input.Reset()
+				return m, nil
+			}
+			m.input, cmd = m.input.Update(msg)
+			return m, cmd
+		}
+		if m.confirmDelete != nil {
+			i := *m.confirmDelete
+			m.confirmDelete = nil
+			if key.Matches(msg, actionKeys.confirm) {
+				return m, deleteHighlight(m.ctx, m.client, i.highlight.HighlightID)
+			}
+			return m, nil
+		}
+		if m.list.FilterState() != list.Filtering {
+			switch {
+			case key.Matches(msg, highlightKeys.add):
+				return m.startAdding("")
+			case key.Matches(msg, highlightKeys.delete):
+				if i, ok := m.list.SelectedItem().(highlightItem); ok {
+					m.confirmDelete = &i
+				}
+				return m, nil
+			}
+		}
+		m.list, cmd = m.list.Update(msg)
+		return m, cmd
+	case highlightsMsg:
+		if msg.bookmarkID != m.bookmark.BookmarkID {
+			return m, nil
+		}
+		m.loading = false
+		items := []list.Item{}
+		for _, h := range msg.highlights {
+			items = append(items, highlightItem{highlight: h})
+		}
+		return m, m.list.SetItems(items)
+	case highlightCreatedMsg:
+		m.err = nil
+		if msg.BookmarkID != m.bookmark.BookmarkID {
+			return m, nil
+		}
+		return m, m.list.InsertItem(len(m.list.Items()), highlightItem{highlight: instapaper.Highlight(msg)})
+	case highlightDeletedMsg:
+		m.err = nil
+		for i, li := range m.list.Items() {
+			if li.(highlightItem).highlight.HighlightID == msg.highlightID {
+				m.list.RemoveItem(i)
+				break
+			}
+		}
+	case highlightsErrMsg:
+		m.loading = false
+		m.err = msg.err
+	}
+	return m, nil
+}
+
+func (m highlightsModel) View() string {
+	status := ""
+	switch {
+	case m.confirmDelete != nil:
+		status = fmt.Sprintf("delete highlight %q? y/n", m.confirmDelete.highlight.Text)
+	case m.err != nil:
+		status = errStyle.Render(m.err.Error())
+	case m.loading:
+		status = "Loading highlights..."
+	case len(m.list.Items()) == 0:
+		status = "No highlights yet"
+	}
+	input := ""
+	if m.input.Focused() {
+		input = m.input.View()
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, m.list.View(), input, status)
+}
+
+func (m highlightsModel) ShortHelp() []key.Binding {
+	if m.confirmDelete != nil {
+		return []key.Binding{actionKeys.confirm, actionKeys.cancel}
+	}
+	if m.input.Focused() {
+		return []key.Binding{highlightKeys.save, highlightKeys.back}
+	}
+	return append(m.list.ShortHelp(), highlightKeys.add, highlightKeys.delete, highlightKeys.back)
+}
+
+func (m highlightsModel) FullHelp() [][]key.Binding {
+	if m.confirmDelete != nil {
+		return [][]key.Binding{{actionKeys.confirm, actionKeys.cancel}}
+	}
+	if m.input.Focused() {
+		return [][]key.Binding{{highlightKeys.save, highlightKeys.back}}
+	}
+	return append(m.list.FullHelp(), []key.Binding{highlightKeys.add, highlightKeys.delete, highlightKeys.back})
+}
+
+// misc helper functions
+
+// blockElements start a new line in the reader, the text around them is not joined
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "br": true, "dd": true,
+	"div": true, "dl": true, "dt": true, "figcaption": true, "figure": true, "footer": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "header": true,
+	"hr": true, "li": true, "main": true, "ol": true, "p": true, "pre": true, "section": true,
+	"table": true, "td": true, "th": true, "tr": true, "ul": true,
+}
+
+// highlightPosition finds the text in the article, Instapaper anchors the highlight there
+func highlightPosition(ctx context.Context, client instapaper.Client, c *cache.Cache, bookmark instapaper.Bookmark, text string) (int, error) {
+	article, err := c.Text(bookmark)
+	if err != nil {
+		if article, err = client.GetBookmarkText(ctx, bookmark.BookmarkID); err != nil {
+			return 0, fmt.Errorf("failed to get the article: %w", err)
+		}
+	}
+	position := textPosition(article, text)
+	if position < 0 {
+		// the reader shows headings and list items with markdown markers
+		position = textPosition(article, strings.TrimLeft(text, "#*>-• "))
+	}
+	if position < 0 {
+		return 0, errors.New("the text is not in the article")
+	}
+	return position, nil
+}
+
+// textPosition is the offset in characters of text in the text of the article HTML,
+// or -1 if it is not there. Runs of whitespace count as one space.
+func textPosition(article, text string) int {
+	plain := strings.Join(strings.Fields(articleText(article)), " ")
+	text = strings.Join(strings.Fields(text), " ")
+	i := strings.Index(plain, text)
+	if i < 0 || text == "" {
+		return -1
+	}
+	return utf8.RuneCountInString(plain[:i])
+}
+
+// articleText is the text of the article HTML without markup, scripts and styles. Text in inline
+// elements is joined as shown, e.g. "an <em>important</em> word.", blocks are separated by a space.
+func articleText(article string) string {
+	z := html.NewTokenizer(strings.NewReader(article))
+	var b strings.Builder
+	skip := 0
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return b.String()
+		}
+		name, _ := z.TagName()
+		switch {
+		case tt == html.TextToken && skip == 0:
+			b.Write(z.Text())
+		case tt == html.StartTagToken && (string(name) == "script" || string(name) == "style"):
+			skip++
+		case tt == html.EndTagToken && (string(name) == "script" || string(name) == "style") && skip > 0:
+			skip--
+		case tt != html.TextToken && blockElements[string(name)]:
+			b.WriteByte(' ')
+		}
+	}
+}
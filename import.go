@@ -0,0 +1,343 @@
+// importing bookmarks exported from other apps
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/ieroNo47/gopaper/internal/instapaper"
+	"github.com/ieroNo47/gopaper/internal/ledger"
+)
+
+// import actions, dry runs report the same actions without taking them
+const (
+	importAdd = "add"
+	// importUpdate stars, archives or moves a bookmark added by an interrupted import
+	importUpdate = "update"
+	// importExists skips a URL that is saved already
+	importExists = "exists"
+	// importDone skips a URL that was imported before
+	importDone    = "done"
+	importInvalid = "invalid"
+)
+
+// importResult is what an import did with a bookmark of the export
+type importResult struct {
+	Action     string   `json:"action"`
+	BookmarkID int64    `json:"bookmark_id,omitempty"`
+	URL        string   `json:"url"`
+	Title      string   `json:"title"`
+	Folder     string   `json:"folder,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	Archived   bool     `json:"archived,omitempty"`
+	Starred    bool     `json:"starred,omitempty"`
+}
+
+// savedBookmark is a bookmark of the account with the ID of its folder
+type savedBookmark struct {
+	bookmark instapaper.Bookmark
+	folderID string
+}
+
+// importer saves the bookmarks of an export that are not saved yet. Every added URL is recorded
+// in the ledger, so an interrupted import continues where it stopped when it is run again.
+type importer struct {
+	ctx    context.Context
+	client instapaper.Client
+	ledger *ledger.Ledger
+	dryRun bool
+	// saved are the bookmarks of all folders by URL
+	saved map[string]savedBookmark
+	// folders are the IDs of the user-created folders by lowercase title
+	folders map[string]int64
+	// newFolders are the folders created by the import, or to be created in a dry run
+	newFolders []string
+	imported   map[string]int64
+}
+
+// newImporter loads the bookmarks and folders of the account to dedupe against
+func newImporter(ctx context.Context, client instapaper.Client, l *ledger.Ledger, dryRun bool) (*importer, error) {
+	imported, err := l.Imported()
+	if err != nil {
+		return nil, err
+	}
+	folders, err := client.ListFolders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	im := &importer{
+		ctx:      ctx,
+		client:   client,
+		ledger:   l,
+		dryRun:   dryRun,
+		saved:    map[string]savedBookmark{},
+		folders:  map[string]int64{},
+		imported: imported,
+	}
+	folderIDs := []string{instapaper.FolderUnread, instapaper.FolderArchive}
+	for _, f := range folders {
+		im.folders[strings.ToLower(f.Title)] = f.FolderID
+		folderIDs = append(folderIDs, f.ID())
+	}
+	for _, folderID := range folderIDs {
+		response, err := client.ListAllBookmarks(ctx, instapaper.ListOptions{FolderID: folderID})
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range response.Bookmarks {
+			im.saved[b.URL] = savedBookmark{bookmark: b, folderID: folderID}
+		}
+	}
+	return im, nil
+}
+
+// run imports the items in order and reports what happened, up to the first error.
+// The number of handled items is written to progress.
+func (im *importer) run(items []importItem, progress io.Writer) ([]importResult, error) {
+	results := []importResult{}
+	defer func() {
+		if len(items) > 0 {
+			fmt.Fprintln(progress)
+		}
+	}()
+	for i, item := range items {
+		fmt.Fprintf(progress, "\r%d/%d", i+1, len(items))
+		result, err := im.importItem(item)
+		results = append(results, result)
+		if err != nil {
+			return results, fmt.Errorf("failed to import %s: %w", item.URL, err)
+		}
+	}
+	return results, nil
+}
+
+func (im *importer) importItem(item importItem) (importResult, error) {
+	result := importResult{
+		URL:      item.URL,
+		Title:    item.Title,
+		Folder:   item.Folder,
+		Tags:     item.Tags,
+		Archived: item.Archived,
+		Starred:  item.Starred,
+	}
+	if u, err := url.Parse(item.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		result.Action = importInvalid
+		return result, nil
+	}
+	saved, ok := im.saved[item.URL]
+	result.BookmarkID = saved.bookmark.BookmarkID
+	_, imported := im.imported[importKey(item.URL)]
+	switch {
+	case imported && !ok:
+		// deleted since it was imported
+		result.Action = importDone
+		return result, nil
+	case imported:
+		update, err := im.needsUpdate(item, saved)
+		if err != nil || !update {
+			result.Action = importDone
+			return result, err
+		}
+		result.Action = importUpdate
+		if im.dryRun {
+			return result, nil
+		}
+		return result, im.setState(item, saved)
+	case ok:
+		result.Action = importExists
+		return result, nil
+	}
+
+	result.Action = importAdd
+	params := instapaper.AddBookmarkParams{URL: item.URL, Title: item.Title, Description: item.Description, Tags: item.Tags}
+	if item.Folder != "" && !item.Archived {
+		folderID, err := im.folderID(item.Folder)
+		if err != nil {
+			return result, err
+		}
+		params.FolderID = folderID
+	}
+	if im.dryRun {
+		// later duplicates in the export are reported as existing
+		im.saved[item.URL] = savedBookmark{}
+		return result, nil
+	}
+	bookmark, err := im.client.AddBookmark(im.ctx, params)
+	if err != nil {
+		if errors.Is(err, instapaper.ErrInvalidURL) {
+			result.Action = importInvalid
+			return result, nil
+		}
+		return result, err
+	}
+	result.BookmarkID = bookmark.BookmarkID
+	saved = savedBookmark{bookmark: bookmark, folderID: instapaper.FolderUnread}
+	if params.FolderID != 0 {
+		saved.folderID = strconv.FormatInt(params.FolderID, 10)
+	}
+	im.saved[item.URL] = saved
+	if err := im.ledger.Save(importKey(item.URL), bookmark.BookmarkID); err != nil {
+		return result, err
+	}
+	im.imported[importKey(item.URL)] = bookmark.BookmarkID
+	return result, im.setState(item, saved)
+}
+
+// needsUpdate reports whether a bookmark is not starred, archived or in the folder of the item yet
+func (im *importer) needsUpdate(item importItem, saved savedBookmark) (bool, error) {
+	if item.Starred && saved.bookmark.Starred != "1" {
+		return true, nil
+	}
+	target, err := im.targetFolder(item)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case target == saved.folderID:
+		return false, nil
+	case target == instapaper.FolderUnread:
+		// bookmarks in a user-created folder stay there, see setState
+		return saved.folderID == instapaper.FolderArchive, nil
+	}
+	return true, nil
+}
+
+// setState stars, archives or moves a bookmark unless it is in that state already
+func (im *importer) setState(item importItem, saved savedBookmark) error {
+	id := saved.bookmark.BookmarkID
+	if item.Starred && saved.bookmark.Starred != "1" {
+		if _, err := im.client.StarBookmark(im.ctx, id); err != nil {
+			return err
+		}
+	}
+	target, err := im.targetFolder(item)
+	if err != nil || target == saved.folderID {
+		return err
+	}
+	switch target {
+	case instapaper.FolderArchive:
+		_, err = im.client.ArchiveBookmark(im.ctx, id)
+	case instapaper.FolderUnread:
+		// bookmarks in a user-created folder stay there
+		if saved.folderID == instapaper.FolderArchive {
+			_, err = im.client.UnarchiveBookmark(im.ctx, id)
+		}
+	default:
+		folderID, _ := strconv.ParseInt(target, 10, 64)
+		_, err = im.client.MoveBookmark(im.ctx, id, folderID)
+	}
+	return err
+}
+
+// targetFolder is the ID of the folder an item belongs in, archived items are in the archive
+func (im *importer) targetFolder(item importItem) (string, error) {
+	switch {
+	case item.Archived:
+		return instapaper.FolderArchive, nil
+	case item.Folder == "":
+		return instapaper.FolderUnread, nil
+	}
+	folderID, err := im.folderID(item.Folder)
+	if err != nil || folderID == 0 {
+		return "", err
+	}
+	return strconv.FormatInt(folderID, 10), nil
+}
+
+// folderID returns the ID of a user-created folder and creates it if it does not exist.
+// Dry runs only note the new folder and return 0.
+func (im *importer) folderID(title string) (int64, error) {
+	if id, ok := im.folders[strings.ToLower(title)]; ok {
+		return id, nil
+	}
+	if im.dryRun {
+		im.folders[strings.ToLower(title)] = 0
+		im.newFolders = append(im.newFolders, title)
+		return 0, nil
+	}
+	folder, err := im.client.AddFolder(im.ctx, title)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create folder %q: %w", title, err)
+	}
+	im.folders[strings.ToLower(title)] = folder.FolderID
+	im.newFolders = append(im.newFolders, title)
+	return folder.FolderID, nil
+}
+
+// importSummary counts the results by action, e.g. "2 to add, 1 exists" for a dry run
+func importSummary(results []importResult, newFolders []string, dryRun bool) string {
+	counts := map[string]int{}
+	for _, r := range results {
+		counts[r.Action]++
+	}
+	add, update := "added", "updated"
+	if dryRun {
+		add, update = "to add", "to update"
+	}
+	parts := []string{}
+	for _, c := range []struct {
+		action, text string
+	}{
+		{importAdd, add},
+		{importUpdate, update},
+		{importExists, "saved already"},
+		{importDone, "imported before"},
+		{importInvalid, "invalid"},
+	} {
+		if counts[c.action] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[c.action], c.text))
+		}
+	}
+	summary := strings.Join(parts, ", ")
+	if len(newFolders) > 0 {
+		summary += "; new folders: " + strings.Join(newFolders, ", ")
+	}
+	if dryRun {
+		summary = "dry run, nothing was changed: " + summary
+	}
+	return summary
+}
+
+// misc helper functions
+
+// importKey is the key of an imported URL in the ledger
+func importKey(u string) string {
+	return "import:" + u
+}
+
+func printImportResults(w io.Writer, results []importResult) error {
+	switch output {
+	case formatJSON:
+		return printJSON(w, results)
+	case formatPlain:
+		for _, r := range results {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", r.Action, r.BookmarkID, r.Title, r.URL)
+		}
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ACTION\tID\tTITLE\tURL\tFOLDER\tSTATE\tTAGS")
+	for _, r := range results {
+		id := ""
+		if r.BookmarkID != 0 {
+			id = strconv.FormatInt(r.BookmarkID, 10)
+		}
+		state := []string{}
+		if r.Archived {
+			state = append(state, "archived")
+		}
+		if r.Starred {
+			state = append(state, "★")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.Action, id, ansi.Truncate(r.Title, maxTitleWidth, "…"), r.URL, r.Folder, strings.Join(state, " "), strings.Join(r.Tags, ","))
+	}
+	return tw.Flush()
+}
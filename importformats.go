@@ -0,0 +1,315 @@
+// export formats of other read-later apps and browsers
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// import formats, see parseExport
+const (
+	formatNetscape = "netscape"
+	formatPocket   = "pocket"
+	formatWallabag = "wallabag"
+	formatOmnivore = "omnivore"
+	formatCSV      = "csv"
+)
+
+var importFormats = []string{formatNetscape, formatPocket, formatWallabag, formatOmnivore, formatCSV}
+
+// importItem is a bookmark read from an export
+type importItem struct {
+	URL         string
+	Title       string
+	Description string
+	Tags        []string
+	// Folder is the title of a user-created folder, empty for Home
+	Folder   string
+	Archived bool
+	Starred  bool
+}
+
+// parseExport reads the bookmarks of an export in one of the import formats
+func parseExport(format string, data []byte) ([]importItem, error) {
+	switch format {
+	case formatNetscape, formatPocket:
+		return parseHTMLExport(bytes.NewReader(data))
+	case formatWallabag:
+		return parseWallabag(data)
+	case formatOmnivore:
+		return parseOmnivore(data)
+	case formatCSV:
+		return parseCSV(bytes.NewReader(data))
+	}
+	return nil, fmt.Errorf("unknown import format %q, use %s", format, strings.Join(importFormats, ", "))
+}
+
+// detectFormat guesses the format of an export from the file extension and content
+func detectFormat(path string, data []byte) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".html", ".htm":
+		if bytes.Contains(bytes.ToLower(data), []byte("<title>pocket export</title>")) {
+			return formatPocket, nil
+		}
+		return formatNetscape, nil
+	case ".csv":
+		return formatCSV, nil
+	case ".json":
+		var entries []map[string]json.RawMessage
+		if err := json.Unmarshal(data, &entries); err == nil && len(entries) > 0 {
+			switch {
+			case entries[0]["is_archived"] != nil || entries[0]["is_starred"] != nil:
+				return formatWallabag, nil
+			case entries[0]["labels"] != nil || entries[0]["state"] != nil:
+				return formatOmnivore, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("cannot tell the format of %s, use --format %s", filepath.Base(path), strings.Join(importFormats, "|"))
+}
+
+// parseHTMLExport reads the bookmark files of browsers and Pocket exports. Links are in the folder
+// of the innermost heading, Pocket lists the archived links under the "Read Archive" heading.
+func parseHTMLExport(r io.Reader) ([]importItem, error) {
+	z := html.NewTokenizer(r)
+	items := []importItem{}
+	// folders has the folder of every open <dl>, heading is the folder of the next one
+	folders := []string{}
+	heading := ""
+	archived := false
+	// text collects the text of the open element
+	var text strings.Builder
+	textOf := ""
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if errors.Is(z.Err(), io.EOF) {
+				return items, nil
+			}
+			return nil, z.Err()
+		case html.TextToken:
+			if textOf != "" {
+				text.Write(z.Text())
+			}
+			continue
+		}
+		t := z.Token()
+		// a <dd> has the description of the link before it and is not closed
+		if textOf == "dd" && (t.Data == "dt" || t.Data == "dl") {
+			items[len(items)-1].Description = strings.TrimSpace(text.String())
+			textOf = ""
+		}
+		switch {
+		case tt == html.StartTagToken && t.Data == "dl":
+			folder := heading
+			if folder == "" && len(folders) > 0 {
+				folder = folders[len(folders)-1]
+			}
+			folders = append(folders, folder)
+			heading = ""
+		case tt == html.EndTagToken && t.Data == "dl":
+			if len(folders) > 0 {
+				folders = folders[:len(folders)-1]
+			}
+		case tt == html.StartTagToken && t.Data == "a":
+			item := importItem{URL: strings.TrimSpace(attr(t, "href")), Tags: splitTags(attr(t, "tags")), Archived: archived}
+			if len(folders) > 0 {
+				item.Folder = folders[len(folders)-1]
+			}
+			items = append(items, item)
+			text.Reset()
+			textOf = "a"
+		case tt == html.StartTagToken && (t.Data == "h1" || t.Data == "h3"),
+			tt == html.StartTagToken && t.Data == "dd" && len(items) > 0:
+			text.Reset()
+			textOf = t.Data
+			if t.Data == "h3" && attr(t, "personal_toolbar_folder") == "true" {
+				// the bookmarks bar of browsers is not a folder
+				textOf = "toolbar"
+			}
+		case tt == html.EndTagToken && t.Data == textOf,
+			tt == html.EndTagToken && t.Data == "h3" && textOf == "toolbar":
+			value := strings.Join(strings.Fields(text.String()), " ")
+			switch textOf {
+			case "a":
+				items[len(items)-1].Title = value
+			case "h1":
+				archived = strings.EqualFold(value, "Read Archive")
+			case "h3":
+				heading = value
+			case "toolbar":
+				heading = ""
+			}
+			textOf = ""
+		}
+	}
+}
+
+// jsonBool is a boolean that may be encoded as a number, as in Wallabag exports
+type jsonBool bool
+
+func (b *jsonBool) UnmarshalJSON(data []byte) error {
+	switch strings.Trim(string(data), `"`) {
+	case "true", "1":
+		*b = true
+	case "false", "0", "null", "":
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean %s", data)
+	}
+	return nil
+}
+
+type wallabagEntry struct {
+	Title      string   `json:"title"`
+	URL        string   `json:"url"`
+	IsArchived jsonBool `json:"is_archived"`
+	IsStarred  jsonBool `json:"is_starred"`
+	Tags       []string `json:"tags"`
+}
+
+func parseWallabag(data []byte) ([]importItem, error) {
+	var entries []wallabagEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse Wallabag export: %w", err)
+	}
+	items := []importItem{}
+	for _, e := range entries {
+		items = append(items, importItem{
+			URL:      strings.TrimSpace(e.URL),
+			Title:    e.Title,
+			Tags:     e.Tags,
+			Archived: bool(e.IsArchived),
+			Starred:  bool(e.IsStarred),
+		})
+	}
+	return items, nil
+}
+
+type omnivoreEntry struct {
+	Title       string   `json:"title"`
+	URL         string   `json:"url"`
+	Description string   `json:"description"`
+	Labels      []string `json:"labels"`
+	// State is Archived for archived articles
+	State string `json:"state"`
+}
+
+func parseOmnivore(data []byte) ([]importItem, error) {
+	var entries []omnivoreEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse Omnivore export: %w", err)
+	}
+	items := []importItem{}
+	for _, e := range entries {
+		items = append(items, importItem{
+			URL:         strings.TrimSpace(e.URL),
+			Title:       e.Title,
+			Description: e.Description,
+			Tags:        e.Labels,
+			Archived:    strings.EqualFold(e.State, "Archived"),
+		})
+	}
+	return items, nil
+}
+
+// parseCSV reads a CSV file with a header row. Only the url column is required, the other columns
+// are matched by the names used in common exports, e.g. the Instapaper and Pocket CSV exports.
+func parseCSV(r io.Reader) ([]importItem, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	record := []string{}
+	get := func(names ...string) string {
+		for _, name := range names {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+		}
+		return ""
+	}
+	if !slices.ContainsFunc([]string{"url", "href", "link"}, func(name string) bool { _, ok := columns[name]; return ok }) {
+		return nil, errors.New("the CSV file has no url column")
+	}
+	items := []importItem{}
+	for {
+		record, err = cr.Read()
+		if errors.Is(err, io.EOF) {
+			return items, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		item := importItem{
+			URL:         get("url", "href", "link"),
+			Title:       get("title", "name"),
+			Description: get("description", "selection", "excerpt", "note"),
+			Tags:        splitTags(get("tags", "labels")),
+			Folder:      get("folder"),
+			Starred:     isTrue(get("starred", "is_starred", "favorite")),
+		}
+		status := strings.ToLower(get("archived", "is_archived", "status", "state"))
+		item.Archived = isTrue(status) || status == "archive" || status == "archived" || status == "read"
+		// the Instapaper export lists the built-in folders as folders
+		switch strings.ToLower(item.Folder) {
+		case "unread", "home":
+			item.Folder = ""
+		case "archive":
+			item.Folder = ""
+			item.Archived = true
+		case "starred":
+			item.Folder = ""
+			item.Starred = true
+		}
+		items = append(items, item)
+	}
+}
+
+// misc helper functions
+
+func attr(t html.Token, name string) string {
+	for _, a := range t.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// splitTags splits tags separated by commas, | or ;, e.g. "a,b", "a|b" or "[a, b]"
+func splitTags(s string) []string {
+	s = strings.Trim(strings.TrimSpace(s), "[]")
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '|' || r == ';' })
+	tags := []string{}
+	for _, tag := range fields {
+		if tag = strings.Trim(strings.TrimSpace(tag), `"'`); tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func isTrue(s string) bool {
+	switch strings.ToLower(s) {
+	case "1", "true", "yes":
+		return true
+	}
+	return false
+}
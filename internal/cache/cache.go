@@ -0,0 +1,285 @@
+// Local storage of Instapaper data for offline use
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/ieroNo47/gopaper/internal/instapaper"
+)
+
+const appDir = "gopaper"
+
+// ErrNotCached is returned when the requested data has not been stored yet
+var ErrNotCached = errors.New("not cached")
+
+// Cache stores bookmarks, folders, highlights and article text as plain JSON and HTML files:
+//
+//	user.json
+//	folders.json
+//	bookmarks/<folder id>.json
+//	highlights/<bookmark id>.json
+//	text/<bookmark id>_<hash>.html
+//	pending_progress.json
+//
+// Tags are not stored separately since they are part of the bookmarks.
+type Cache struct {
+	dir string
+	// mu guards the read-modify-write of the pending progress
+	mu sync.Mutex
+}
+
+// PendingProgress is a read progress update that could not be sent to the API yet
+type PendingProgress struct {
+	BookmarkID int64   `json:"bookmark_id"`
+	Progress   float64 `json:"progress"`
+	Timestamp  int64   `json:"progress_timestamp"`
+}
+
+// Open returns the cache in $XDG_CACHE_HOME/gopaper, ~/.cache/gopaper by default
+func Open() (*Cache, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find cache dir: %w", err)
+	}
+	return New(filepath.Join(dir, appDir)), nil
+}
+
+// New returns a cache that stores its files in dir
+func New(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Clear removes the bookmarks, folders, highlights and texts, e.g. when the user logs out.
+// The account and the read progress that was not sent yet are kept for the next login,
+// see ClearAll.
+func (c *Cache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries, err := os.ReadDir(c.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to clear cache: %w", err)
+	}
+	for _, e := range entries {
+		if e.Name() == "user.json" || e.Name() == "pending_progress.json" {
+			continue
+		}
+		if err := os.RemoveAll(c.path(e.Name())); err != nil {
+			return fmt.Errorf("failed to clear cache: %w", err)
+		}
+	}
+	return nil
+}
+
+// ClearAll removes everything stored, once another account logged in
+func (c *Cache) ClearAll() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.RemoveAll(c.dir); err != nil {
+		return fmt.Errorf("failed to clear cache: %w", err)
+	}
+	return nil
+}
+
+// User returns the account as of the last time the credentials were verified
+func (c *Cache) User() (instapaper.User, error) {
+	user := instapaper.User{}
+	return user, c.readJSON(c.path("user.json"), &user)
+}
+
+func (c *Cache) SaveUser(user instapaper.User) error {
+	return c.writeJSON(c.path("user.json"), user)
+}
+
+func (c *Cache) Folders() ([]instapaper.Folder, error) {
+	folders := []instapaper.Folder{}
+	return folders, c.readJSON(c.path("folders.json"), &folders)
+}
+
+func (c *Cache) SaveFolders(folders []instapaper.Folder) error {
+	return c.writeJSON(c.path("folders.json"), folders)
+}
+
+// Bookmarks returns the bookmarks of a folder as of the last sync
+func (c *Cache) Bookmarks(folderID string) ([]instapaper.Bookmark, error) {
+	bookmarks := []instapaper.Bookmark{}
+	return bookmarks, c.readJSON(c.path("bookmarks", folderID+".json"), &bookmarks)
+}
+
+func (c *Cache) SaveBookmarks(folderID string, bookmarks []instapaper.Bookmark) error {
+	return c.writeJSON(c.path("bookmarks", folderID+".json"), bookmarks)
+}
+
+func (c *Cache) Highlights(bookmarkID int64) ([]instapaper.Highlight, error) {
+	highlights := []instapaper.Highlight{}
+	return highlights, c.readJSON(c.path("highlights", idFile(bookmarkID, ".json")), &highlights)
+}
+
+func (c *Cache) SaveHighlights(bookmarkID int64, highlights []instapaper.Highlight) error {
+	return c.writeJSON(c.path("highlights", idFile(bookmarkID, ".json")), highlights)
+}
+
+// Text returns the article HTML of a bookmark. The text is stored per bookmark hash,
+// so it is not returned anymore once the bookmark changed on the server.
+func (c *Cache) Text(bookmark instapaper.Bookmark) (string, error) {
+	data, err := os.ReadFile(c.textPath(bookmark))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrNotCached
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read cached text: %w", err)
+	}
+	return string(data), nil
+}
+
+// HasText reports whether the current version of the article is cached
+func (c *Cache) HasText(bookmark instapaper.Bookmark) bool {
+	_, err := os.Stat(c.textPath(bookmark))
+	return err == nil
+}
+
+// SaveText stores the article HTML and drops versions stored for older hashes
+func (c *Cache) SaveText(bookmark instapaper.Bookmark, html string) error {
+	c.removeText(bookmark.BookmarkID)
+	return c.writeFile(c.textPath(bookmark), []byte(html))
+}
+
+// PendingProgress returns the queued read progress updates
+func (c *Cache) PendingProgress() ([]PendingProgress, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pendingProgress()
+}
+
+// QueueProgress stores a read progress update to be sent later.
+// It replaces any update queued for the same bookmark.
+func (c *Cache) QueueProgress(progress PendingProgress) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pending, err := c.pendingProgress()
+	if err != nil {
+		return err
+	}
+	queued := []PendingProgress{progress}
+	for _, p := range pending {
+		if p.BookmarkID != progress.BookmarkID {
+			queued = append(queued, p)
+		}
+	}
+	return c.writeJSON(c.path("pending_progress.json"), queued)
+}
+
+// RemovePendingProgress removes a queued update after it was sent.
+// Updates that were queued again in the meantime are kept.
+func (c *Cache) RemovePendingProgress(progress PendingProgress) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pending, err := c.pendingProgress()
+	if err != nil {
+		return err
+	}
+	queued := []PendingProgress{}
+	for _, p := range pending {
+		if p != progress {
+			queued = append(queued, p)
+		}
+	}
+	return c.writeJSON(c.path("pending_progress.json"), queued)
+}
+
+// Remove deletes everything stored for a bookmark
+func (c *Cache) Remove(bookmarkID int64) error {
+	c.removeText(bookmarkID)
+	err := os.Remove(c.path("highlights", idFile(bookmarkID, ".json")))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// misc helper functions
+
+func (c *Cache) path(elem ...string) string {
+	return filepath.Join(append([]string{c.dir}, elem...)...)
+}
+
+func (c *Cache) textPath(bookmark instapaper.Bookmark) string {
+	return c.path("text", fmt.Sprintf("%d_%s.html", bookmark.BookmarkID, bookmark.Hash))
+}
+
+func (c *Cache) removeText(bookmarkID int64) {
+	old, _ := filepath.Glob(c.path("text", fmt.Sprintf("%d_*.html", bookmarkID)))
+	for _, path := range old {
+		os.Remove(path)
+	}
+}
+
+func (c *Cache) pendingProgress() ([]PendingProgress, error) {
+	pending := []PendingProgress{}
+	err := c.readJSON(c.path("pending_progress.json"), &pending)
+	if errors.Is(err, ErrNotCached) {
+		return pending, nil
+	}
+	return pending, err
+}
+
+func idFile(id int64, ext string) string {
+	return strconv.FormatInt(id, 10) + ext
+}
+
+func (c *Cache) readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotCached
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read cache: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse cache file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Cache) writeJSON(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.writeFile(path, data)
+}
+
+// writeFile replaces a file atomically so readers never see a partial write
+func (c *Cache) writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create cache dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+// Client configuration
+package instapaper
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultBaseURL    = "https://www.instapaper.com/api"
+	DefaultAPIVersion = "1.1"
+	defaultTimeout    = 10 * time.Second
+)
+
+// Config configures a Client. Zero values are replaced by the defaults.
+type Config struct {
+	// BaseURL is the API root without the version, defaults to DefaultBaseURL
+	BaseURL string
+	// APIVersion defaults to DefaultAPIVersion
+	APIVersion string
+	// ConsumerKey and ConsumerSecret identify the application, they are issued by Instapaper
+	ConsumerKey    string
+	ConsumerSecret string
+	// Token is the access token of the user, see Login
+	Token Token
+	// HTTPClient is used to send the requests, its transport is wrapped to sign them.
+	// Defaults to a new client with a 10 second timeout.
+	HTTPClient *http.Client
+	// Timeout overrides the timeout of HTTPClient if set
+	Timeout time.Duration
+	// UserAgent is sent with every request if set
+	UserAgent string
+	// MaxRetries is how often a failed request is retried, defaults to 3. Negative disables retries.
+	MaxRetries int
+	// RetryBackoff is the wait before the first retry, it doubles with every retry. Defaults to 500ms.
+	RetryBackoff time.Duration
+}
+
+type Option func(*Config)
+
+// WithHTTPClient sets the client used to send requests
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Config) {
+		c.HTTPClient = httpClient
+	}
+}
+
+// WithBaseURL sets the API root, e.g. to use a local test server
+func WithBaseURL(baseURL string) Option {
+	return func(c *Config) {
+		c.BaseURL = baseURL
+	}
+}
+
+func WithAPIVersion(version string) Option {
+	return func(c *Config) {
+		c.APIVersion = version
+	}
+}
+
+// WithCredentials sets the OAuth consumer key and secret of the application
+func WithCredentials(consumerKey, consumerSecret string) Option {
+	return func(c *Config) {
+		c.ConsumerKey = consumerKey
+		c.ConsumerSecret = consumerSecret
+	}
+}
+
+// WithToken sets the access token of the user
+func WithToken(token Token) Option {
+	return func(c *Config) {
+		c.Token = token
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.Timeout = timeout
+	}
+}
+
+func WithUserAgent(userAgent string) Option {
+	return func(c *Config) {
+		c.UserAgent = userAgent
+	}
+}
+
+// WithRetries sets how often failed requests are retried and the wait before the first retry
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Config) {
+		c.MaxRetries = maxRetries
+		c.RetryBackoff = backoff
+	}
+}
+
+// WithConfig replaces the whole configuration, later options still apply on top of it
+func WithConfig(cfg Config) Option {
+	return func(c *Config) {
+		*c = cfg
+	}
+}
+
+// WithEnv reads the configuration from the environment variables that are set:
+//
+//	IP_API                    base URL
+//	IP_API_VERSION            API version
+//	IP_OAUTH_CONSUMER_ID      consumer key
+//	IP_OAUTH_CONSUMER_SECRET  consumer secret
+func WithEnv() Option {
+	return func(c *Config) {
+		if v := os.Getenv("IP_API"); v != "" {
+			c.BaseURL = v
+		}
+		if v := os.Getenv("IP_API_VERSION"); v != "" {
+			c.APIVersion = v
+		}
+		if v := os.Getenv("IP_OAUTH_CONSUMER_ID"); v != "" {
+			c.ConsumerKey = v
+		}
+		if v := os.Getenv("IP_OAUTH_CONSUMER_SECRET"); v != "" {
+			c.ConsumerSecret = v
+		}
+	}
+}
+
+// misc helper functions
+
+func newConfig(opts []Option) Config {
+	cfg := Config{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg.withDefaults()
+}
+
+func (c Config) withDefaults() Config {
+	if c.BaseURL == "" {
+		c.BaseURL = DefaultBaseURL
+	}
+	c.BaseURL = strings.TrimSuffix(c.BaseURL, "/")
+	if c.APIVersion == "" {
+		c.APIVersion = DefaultAPIVersion
+	}
+	return c
+}
+
+func (c Config) validate() error {
+	if c.ConsumerKey == "" || c.ConsumerSecret == "" {
+		return errors.New("missing OAuth consumer key or secret")
+	}
+	if _, err := url.Parse(c.BaseURL); err != nil {
+		return fmt.Errorf("invalid base URL %q: %w", c.BaseURL, err)
+	}
+	return nil
+}
+
+// endpointURL returns the full URL of an API endpoint
+func (c Config) endpointURL(endpoint string) string {
+	return fmt.Sprintf("%s/%s/%s", c.BaseURL, c.APIVersion, endpoint)
+}
+
+// httpClient returns a copy of the configured client with the timeout and user agent applied
+func (c Config) httpClient() *http.Client {
+	httpClient := &http.Client{Timeout: defaultTimeout}
+	if c.HTTPClient != nil {
+		copied := *c.HTTPClient
+		httpClient = &copied
+	}
+	if c.Timeout > 0 {
+		httpClient.Timeout = c.Timeout
+	}
+	if c.UserAgent != "" {
+		httpClient.Transport = &userAgentTransport{base: httpClient.Transport, userAgent: c.UserAgent}
+	}
+	return httpClient
+}
+
+// userAgentTransport sets the User-Agent header of every request
+type userAgentTransport struct {
+	base      http.RoundTripper
+	userAgent string
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	// a RoundTripper must not modify the request
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	return base.RoundTrip(req)
+}
@@ -0,0 +1,159 @@
+// API errors
+package instapaper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ieroNo47/gopaper/internal/instapaper/xauth"
+)
+
+// Errors to check an *APIError against with errors.Is. The rate limit and service errors
+// are the same as the ones xauth returns, so they can be checked the same way for the login.
+var (
+	// ErrUnauthorized is returned when the API rejects the access token, e.g. because it was revoked
+	ErrUnauthorized = errors.New("unauthorized: the access token is invalid or revoked")
+	// ErrRateLimited is error 1040 or a 429 response, see also RateLimitError
+	ErrRateLimited = xauth.ErrRateLimited
+	// ErrPremiumRequired is error 1041, the endpoint needs a premium account
+	ErrPremiumRequired = errors.New("premium account required")
+	// ErrContentRequired is error 1220, the domain only allows saving with the content supplied
+	ErrContentRequired = errors.New("domain requires full content to be supplied")
+	ErrInvalidURL      = errors.New("invalid URL specified")
+	ErrInvalidBookmark = errors.New("invalid or missing bookmark_id")
+	ErrInvalidFolder   = errors.New("invalid or missing folder_id")
+	ErrInvalidProgress = errors.New("invalid or missing progress")
+	// ErrPrivateContentRequired is error 1245, private bookmarks must be saved with their content
+	ErrPrivateContentRequired = errors.New("private bookmarks require supplied content")
+	// ErrSaveFailed is error 1250, an unexpected error when saving a bookmark
+	ErrSaveFailed = errors.New("unexpected error when saving bookmark")
+	// ErrServiceUnavailable is error 1500 or a 5xx response
+	ErrServiceUnavailable = xauth.ErrServiceUnavailable
+	// ErrPrivateBookmark is returned when tagging a private bookmark, the tags are saved by URL and
+	// private bookmarks have none
+	ErrPrivateBookmark = errors.New("private bookmarks cannot be tagged")
+	// ErrTextUnavailable is error 1550, Instapaper could not generate the text version of the URL
+	ErrTextUnavailable = errors.New("error generating text version of this URL")
+)
+
+// Instapaper error codes
+const (
+	codeRateLimited     = 1040
+	codePremiumRequired = 1041
+	codeContentRequired = 1220
+	codeInvalidURL      = 1240
+	codeInvalidBookmark = 1241
+	codeInvalidFolder   = 1242
+	codeInvalidProgress = 1243
+	codePrivateContent  = 1245
+	codeSaveFailed      = 1250
+	codeServiceError    = 1500
+	codeTextUnavailable = 1550
+)
+
+// maxErrorMessageLength truncates error responses that are not from the API
+const maxErrorMessageLength = 200
+
+var codeErrors = map[int]error{
+	codeRateLimited:     ErrRateLimited,
+	codePremiumRequired: ErrPremiumRequired,
+	codeContentRequired: ErrContentRequired,
+	codeInvalidURL:      ErrInvalidURL,
+	codeInvalidBookmark: ErrInvalidBookmark,
+	codeInvalidFolder:   ErrInvalidFolder,
+	codeInvalidProgress: ErrInvalidProgress,
+	codePrivateContent:  ErrPrivateContentRequired,
+	codeSaveFailed:      ErrSaveFailed,
+	codeServiceError:    ErrServiceUnavailable,
+	codeTextUnavailable: ErrTextUnavailable,
+}
+
+// APIError is a failed request. Code and Message come from the error object in the response,
+// e.g. {"type": "error", "error_code": 1240, "message": "Invalid URL specified"}.
+// Code is 0 if the response had no error object, Message is then the response body.
+type APIError struct {
+	Code       int    `json:"error_code"`
+	Message    string `json:"message"`
+	StatusCode int    `json:"-"`
+}
+
+func (e *APIError) Error() string {
+	if e.Code == 0 {
+		msg := e.Message
+		if msg == "" {
+			msg = http.StatusText(e.StatusCode)
+		}
+		return fmt.Sprintf("instapaper error (status %d): %s", e.StatusCode, msg)
+	}
+	return fmt.Sprintf("instapaper error %d: %s", e.Code, e.Message)
+}
+
+// Is matches the Err* value for the error code or, without a code, the status code
+func (e *APIError) Is(target error) bool {
+	if err, ok := codeErrors[e.Code]; ok && err == target {
+		return true
+	}
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServiceUnavailable:
+		return e.Code == 0 && e.StatusCode >= 500
+	}
+	return false
+}
+
+// Temporary reports whether the request may succeed when sent again later,
+// after waiting or after logging in again
+func (e *APIError) Temporary() bool {
+	return errors.Is(e, ErrRateLimited) || errors.Is(e, ErrServiceUnavailable) || errors.Is(e, ErrUnauthorized)
+}
+
+// misc helper functions
+
+// newAPIError returns the error of a response, or nil if the request succeeded
+func newAPIError(statusCode int, body []byte) *APIError {
+	if apiErr := parseAPIError(body); apiErr != nil {
+		apiErr.StatusCode = statusCode
+		return apiErr
+	}
+	if statusCode == http.StatusOK {
+		return nil
+	}
+	msg := string(bytes.TrimSpace(body))
+	if len(msg) > maxErrorMessageLength {
+		// e.g. the HTML error page of a proxy
+		msg = msg[:maxErrorMessageLength] + "…"
+	}
+	return &APIError{StatusCode: statusCode, Message: msg}
+}
+
+// parseAPIError returns the first error object found in a response body, or nil.
+// The API returns either a single object or an array of objects.
+func parseAPIError(body []byte) *APIError {
+	body = bytes.TrimSpace(body)
+	items := []responseItem{}
+	switch {
+	case bytes.HasPrefix(body, []byte("[")):
+		if err := json.Unmarshal(body, &items); err != nil {
+			return nil
+		}
+	case bytes.HasPrefix(body, []byte("{")):
+		var item responseItem
+		if err := json.Unmarshal(body, &item); err != nil {
+			return nil
+		}
+		items = append(items, item)
+	}
+	for _, item := range items {
+		if item.Type == "error" {
+			apiErr := item.APIError
+			return &apiErr
+		}
+	}
+	return nil
+}
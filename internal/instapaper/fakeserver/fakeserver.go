@@ -0,0 +1,735 @@
+// Package fakeserver is an in-memory fake of the Instapaper Full API,
+// for integration tests and offline development.
+//
+// Requests must be signed with ConsumerKey and ConsumerSecret. Log in with
+// Username and Password through xAuth, or skip the login with IssueToken.
+package fakeserver
+
+import (
+	"cmp"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ieroNo47/gopaper/internal/instapaper"
+)
+
+// credentials accepted by the server
+const (
+	ConsumerKey    = "fake-consumer-key"
+	ConsumerSecret = "fake-consumer-secret"
+	Username       = "reader@example.com"
+	Password       = "correct horse battery staple"
+)
+
+// BasePath is the API root on the server, the base URL of a client is the server URL plus BasePath
+const BasePath = "/api"
+
+const apiPrefix = BasePath + "/" + instapaper.DefaultAPIVersion + "/"
+
+// error codes returned by the API
+const (
+	codeInvalidURL         = 1240
+	codeInvalidBookmarkID  = 1241
+	codeInvalidFolderID    = 1242
+	codeInvalidProgress    = 1243
+	codePrivateContent     = 1245
+	codeDuplicateFolder    = 1251
+	codeTextUnavailable    = 1550
+	codeEmptyHighlight     = 1600
+	codeDuplicateHighlight = 1601
+)
+
+const (
+	defaultListLimit = 25
+	maxListLimit     = 500
+)
+
+type bookmark struct {
+	instapaper.Bookmark
+	// folder is instapaper.FolderUnread, instapaper.FolderArchive or the ID of a user folder
+	folder string
+	text   string
+}
+
+// Server is a fake Instapaper API. It implements http.Handler, serve it with httptest.NewServer.
+type Server struct {
+	mu         sync.Mutex
+	mux        *http.ServeMux
+	user       instapaper.User
+	tokens     map[string]string // access token -> token secret
+	bookmarks  map[int64]*bookmark
+	folders    []instapaper.Folder
+	highlights []instapaper.Highlight
+	// tags are the tags of the user by name, created when they are first used
+	tags     map[string]instapaper.Tag
+	nextID   int64
+	requests int
+	failures []Failure
+}
+
+// Failure is an error response sent instead of handling a request, see FailNext
+type Failure struct {
+	Status int
+	// Code is the Instapaper error code of the error object in the body, no body is sent if 0
+	Code int
+	// RetryAfter is sent as the Retry-After header if set
+	RetryAfter string
+}
+
+// New returns a server seeded with the fixtures
+func New() *Server {
+	s := &Server{
+		mux:    http.NewServeMux(),
+		tokens: map[string]string{},
+		nextID: 1000,
+	}
+	s.seed()
+
+	s.mux.HandleFunc("POST "+apiPrefix+"oauth/access_token", s.accessToken)
+	s.handle("account/verify_credentials", s.verifyCredentials)
+	s.handle("bookmarks/list", s.listBookmarks)
+	s.handle("bookmarks/get_text", s.getText)
+	s.handle("bookmarks/add", s.addBookmark)
+	s.handle("bookmarks/delete", s.deleteBookmark)
+	s.handle("bookmarks/star", s.setStarred("1"))
+	s.handle("bookmarks/unstar", s.setStarred("0"))
+	s.handle("bookmarks/archive", s.setFolder(instapaper.FolderArchive))
+	s.handle("bookmarks/unarchive", s.setFolder(instapaper.FolderUnread))
+	s.handle("bookmarks/move", s.moveBookmark)
+	s.handle("bookmarks/update_read_progress", s.updateReadProgress)
+	s.handle("folders/list", s.listFolders)
+	s.handle("folders/add", s.addFolder)
+	s.handle("folders/delete", s.deleteFolder)
+	s.handle("folders/set_order", s.setFolderOrder)
+	s.handle("bookmarks/{id}/highlights", s.listHighlights)
+	s.handle("bookmarks/{id}/highlight", s.createHighlight)
+	s.handle("highlights/{id}/delete", s.deleteHighlight)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests++
+	var failure *Failure
+	if len(s.failures) > 0 {
+		failure = &s.failures[0]
+		s.failures = s.failures[1:]
+	}
+	s.mu.Unlock()
+	if failure != nil {
+		if failure.RetryAfter != "" {
+			w.Header().Set("Retry-After", failure.RetryAfter)
+		}
+		if failure.Code == 0 {
+			w.WriteHeader(failure.Status)
+			return
+		}
+		writeErrorStatus(w, failure.Status, failure.Code, http.StatusText(failure.Status))
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// FailNext makes the next n requests fail with f, e.g. to test retries
+func (s *Server) FailNext(n int, f Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for range n {
+		s.failures = append(s.failures, f)
+	}
+}
+
+// Requests returns the number of requests received so far, including failed ones
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// ClientOptions returns the options to use the server at serverURL, e.g. httptest.Server.URL
+func ClientOptions(serverURL string) []instapaper.Option {
+	return []instapaper.Option{
+		instapaper.WithBaseURL(serverURL + BasePath),
+		instapaper.WithCredentials(ConsumerKey, ConsumerSecret),
+	}
+}
+
+// IssueToken returns a valid access token without going through xAuth
+func (s *Server) IssueToken() instapaper.Token {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.issueToken()
+}
+
+// RevokeTokens invalidates every access token issued so far
+func (s *Server) RevokeTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = map[string]string{}
+}
+
+// Bookmark returns the current state of a bookmark and its folder
+func (s *Server) Bookmark(id int64) (instapaper.Bookmark, string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.bookmarks[id]
+	if !ok {
+		return instapaper.Bookmark{}, "", false
+	}
+	return b.Bookmark, b.folder, true
+}
+
+func (s *Server) issueToken() instapaper.Token {
+	s.nextID++
+	token := instapaper.Token{
+		Token:  fmt.Sprintf("token-%d", s.nextID),
+		Secret: fmt.Sprintf("secret-%d", s.nextID),
+	}
+	s.tokens[token.Token] = token.Secret
+	return token
+}
+
+// handle registers an endpoint that requires a signed request with a valid access token
+func (s *Server) handle(endpoint string, h func(http.ResponseWriter, *http.Request)) {
+	s.mux.HandleFunc("POST "+apiPrefix+endpoint, func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		oauthParams := parseAuthorization(r.Header.Get("Authorization"))
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		secret, ok := s.tokens[oauthParams.Get("oauth_token")]
+		if !ok || !validSignature(r, oauthParams, secret) {
+			http.Error(w, "Invalid OAuth signature or token", http.StatusUnauthorized)
+			return
+		}
+		h(w, r)
+	})
+}
+
+// accessToken implements xAuth: the username and password are exchanged for an access token
+func (s *Server) accessToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	oauthParams := parseAuthorization(r.Header.Get("Authorization"))
+	if !validSignature(r, oauthParams, "") {
+		http.Error(w, "Invalid OAuth signature", http.StatusUnauthorized)
+		return
+	}
+	if r.PostForm.Get("x_auth_mode") != "client_auth" ||
+		r.PostForm.Get("x_auth_username") != Username ||
+		r.PostForm.Get("x_auth_password") != Password {
+		http.Error(w, "Invalid xAuth credentials.", http.StatusUnauthorized)
+		return
+	}
+	s.mu.Lock()
+	token := s.issueToken()
+	s.mu.Unlock()
+	values := url.Values{}
+	values.Set("oauth_token", token.Token)
+	values.Set("oauth_token_secret", token.Secret)
+	fmt.Fprint(w, values.Encode())
+}
+
+// listResponse is the bookmarks/list response. Unlike instapaper.Response,
+// delete_ids is encoded as a comma-separated string like the real API does.
+type listResponse struct {
+	User       instapaper.User        `json:"user"`
+	Bookmarks  []instapaper.Bookmark  `json:"bookmarks"`
+	Highlights []instapaper.Highlight `json:"highlights"`
+	DeleteIDs  string                 `json:"delete_ids"`
+}
+
+func (s *Server) verifyCredentials(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, []instapaper.User{s.user})
+}
+
+func (s *Server) listBookmarks(w http.ResponseWriter, r *http.Request) {
+	folderID := r.Form.Get("folder_id")
+	if folderID == "" {
+		folderID = instapaper.FolderUnread
+	}
+	if !s.folderExists(folderID) {
+		writeError(w, codeInvalidFolderID, "Invalid or missing folder_id")
+		return
+	}
+	limit := defaultListLimit
+	if l := r.Form.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err == nil && n > 0 {
+			limit = min(n, maxListLimit)
+		}
+	}
+
+	// skip the bookmarks the client already has, unless they changed
+	skip := map[int64]bool{}
+	deleteIDs := []string{}
+	for _, entry := range strings.Split(r.Form.Get("have"), ",") {
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		id, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		b, ok := s.bookmarks[id]
+		if !ok || !b.inFolder(folderID) {
+			deleteIDs = append(deleteIDs, parts[0])
+			continue
+		}
+		if len(parts) == 1 || parts[1] == b.Hash {
+			if len(parts) < 4 || parts[2] == formatProgress(b.Progress) && parts[3] == strconv.FormatInt(b.ProgressTimestamp, 10) {
+				skip[id] = true
+			}
+		}
+	}
+
+	response := listResponse{
+		User:       s.user,
+		Bookmarks:  []instapaper.Bookmark{},
+		Highlights: []instapaper.Highlight{},
+		DeleteIDs:  strings.Join(deleteIDs, ","),
+	}
+	for _, b := range s.sortedBookmarks() {
+		if len(response.Bookmarks) == limit {
+			break
+		}
+		if !b.inFolder(folderID) || skip[b.BookmarkID] {
+			continue
+		}
+		response.Bookmarks = append(response.Bookmarks, b.Bookmark)
+		for _, h := range s.highlights {
+			if h.BookmarkID == b.BookmarkID {
+				response.Highlights = append(response.Highlights, h)
+			}
+		}
+	}
+	writeJSON(w, response)
+}
+
+func (s *Server) getText(w http.ResponseWriter, r *http.Request) {
+	b, ok := s.formBookmark(w, r)
+	if !ok {
+		return
+	}
+	if b.text == "" {
+		writeError(w, codeTextUnavailable, "There was an unexpected error generating the text version of this URL")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, b.text)
+}
+
+func (s *Server) addBookmark(w http.ResponseWriter, r *http.Request) {
+	content := r.Form.Get("content")
+	privateSource := r.Form.Get("is_private_from_source")
+	if privateSource != "" && content == "" {
+		writeError(w, codePrivateContent, "Private bookmarks require supplied content")
+		return
+	}
+	u, err := url.Parse(r.Form.Get("url"))
+	if privateSource != "" && r.Form.Get("url") == "" {
+		// private content has no URL, every upload is a new bookmark
+		u = &url.URL{}
+	} else if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		writeError(w, codeInvalidURL, "Invalid URL specified")
+		return
+	}
+	folder := ""
+	if id := r.Form.Get("folder_id"); id != "" {
+		if s.folderIndex(id) < 0 {
+			writeError(w, codeInvalidFolderID, "Invalid or missing folder_id")
+			return
+		}
+		folder = id
+	}
+	var tags []instapaper.Tag
+	if r.Form.Has("tags") {
+		var names []struct {
+			Name string `json:"name"`
+		}
+		// tags that cannot be parsed are ignored
+		if err := json.Unmarshal([]byte(r.Form.Get("tags")), &names); err == nil {
+			tags = []instapaper.Tag{}
+			for _, n := range names {
+				if n.Name != "" {
+					tags = append(tags, s.tag(n.Name))
+				}
+			}
+		}
+	}
+
+	// adding an existing URL updates the bookmark
+	var b *bookmark
+	for _, existing := range s.bookmarks {
+		if u.String() != "" && existing.URL == u.String() {
+			b = existing
+			break
+		}
+	}
+	if b == nil {
+		s.nextID++
+		b = &bookmark{
+			Bookmark: instapaper.Bookmark{
+				BookmarkID: s.nextID,
+				URL:        u.String(),
+				Title:      u.String(),
+				Time:       time.Now().Unix(),
+				Starred:    "0",
+				Tags:       []instapaper.Tag{},
+				Type:       "bookmark",
+			},
+			folder: instapaper.FolderUnread,
+			text:   fmt.Sprintf("<html><body><p>Saved from <a href=%q>%s</a>.</p></body></html>", u, u),
+		}
+		s.bookmarks[b.BookmarkID] = b
+	}
+	if title := r.Form.Get("title"); title != "" {
+		b.Title = title
+	}
+	if description := r.Form.Get("description"); description != "" {
+		b.Description = description
+	}
+	// adding an existing URL moves it back to Home unless a folder is given
+	b.folder = cmp.Or(folder, instapaper.FolderUnread)
+	if content != "" {
+		b.text = content
+	}
+	if privateSource != "" {
+		b.PrivateSource = privateSource
+		if b.Title == "" {
+			b.Title = "Untitled"
+		}
+	}
+	if tags != nil {
+		b.Tags = tags
+	}
+	b.rehash()
+	writeJSON(w, []instapaper.Bookmark{b.Bookmark})
+}
+
+func (s *Server) deleteBookmark(w http.ResponseWriter, r *http.Request) {
+	b, ok := s.formBookmark(w, r)
+	if !ok {
+		return
+	}
+	delete(s.bookmarks, b.BookmarkID)
+	writeJSON(w, []any{})
+}
+
+func (s *Server) setStarred(starred string) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		b, ok := s.formBookmark(w, r)
+		if !ok {
+			return
+		}
+		b.Starred = starred
+		b.rehash()
+		writeJSON(w, []instapaper.Bookmark{b.Bookmark})
+	}
+}
+
+func (s *Server) setFolder(folder string) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		b, ok := s.formBookmark(w, r)
+		if !ok {
+			return
+		}
+		b.folder = folder
+		b.rehash()
+		writeJSON(w, []instapaper.Bookmark{b.Bookmark})
+	}
+}
+
+func (s *Server) moveBookmark(w http.ResponseWriter, r *http.Request) {
+	b, ok := s.formBookmark(w, r)
+	if !ok {
+		return
+	}
+	folderID := r.Form.Get("folder_id")
+	if s.folderIndex(folderID) < 0 {
+		writeError(w, codeInvalidFolderID, "Invalid or missing folder_id")
+		return
+	}
+	b.folder = folderID
+	b.rehash()
+	writeJSON(w, []instapaper.Bookmark{b.Bookmark})
+}
+
+func (s *Server) updateReadProgress(w http.ResponseWriter, r *http.Request) {
+	b, ok := s.formBookmark(w, r)
+	if !ok {
+		return
+	}
+	progress, err := strconv.ParseFloat(r.Form.Get("progress"), 64)
+	if err != nil || progress < 0 || progress > 1 {
+		writeError(w, codeInvalidProgress, "Invalid or missing progress")
+		return
+	}
+	timestamp, err := strconv.ParseInt(r.Form.Get("progress_timestamp"), 10, 64)
+	if err != nil {
+		writeError(w, codeInvalidProgress, "Invalid or missing progress_timestamp")
+		return
+	}
+	// older updates are ignored, like on the real service
+	if timestamp >= b.ProgressTimestamp {
+		b.Progress = progress
+		b.ProgressTimestamp = timestamp
+	}
+	writeJSON(w, []instapaper.Bookmark{b.Bookmark})
+}
+
+func (s *Server) listFolders(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.sortedFolders())
+}
+
+func (s *Server) addFolder(w http.ResponseWriter, r *http.Request) {
+	title := strings.TrimSpace(r.Form.Get("title"))
+	if title == "" {
+		writeError(w, codeInvalidFolderID, "Invalid or missing title")
+		return
+	}
+	position := 1.0
+	for _, f := range s.folders {
+		if strings.EqualFold(f.Title, title) {
+			writeError(w, codeDuplicateFolder, "User already has a folder with this title")
+			return
+		}
+		position = max(position, f.Position+1)
+	}
+	s.nextID++
+	folder := instapaper.Folder{
+		FolderID:     s.nextID,
+		Title:        title,
+		DisplayTitle: title,
+		Slug:         slug(title),
+		SyncToMobile: 1,
+		Position:     position,
+		Type:         "folder",
+	}
+	s.folders = append(s.folders, folder)
+	writeJSON(w, []instapaper.Folder{folder})
+}
+
+// deleteFolder deletes a folder, the bookmarks in it are moved to the archive
+func (s *Server) deleteFolder(w http.ResponseWriter, r *http.Request) {
+	folderID := r.Form.Get("folder_id")
+	i := s.folderIndex(folderID)
+	if i < 0 {
+		writeError(w, codeInvalidFolderID, "Invalid or missing folder_id")
+		return
+	}
+	s.folders = append(s.folders[:i], s.folders[i+1:]...)
+	for _, b := range s.bookmarks {
+		if b.folder == folderID {
+			b.folder = instapaper.FolderArchive
+			b.rehash()
+		}
+	}
+	writeJSON(w, []any{})
+}
+
+func (s *Server) setFolderOrder(w http.ResponseWriter, r *http.Request) {
+	for _, pair := range strings.Split(r.Form.Get("order"), ",") {
+		id, position, found := strings.Cut(pair, ":")
+		i := s.folderIndex(id)
+		p, err := strconv.ParseFloat(position, 64)
+		if !found || i < 0 || err != nil {
+			writeError(w, codeInvalidFolderID, "Invalid or missing folder_id")
+			return
+		}
+		s.folders[i].Position = p
+	}
+	writeJSON(w, s.sortedFolders())
+}
+
+func (s *Server) listHighlights(w http.ResponseWriter, r *http.Request) {
+	b, ok := s.pathBookmark(w, r)
+	if !ok {
+		return
+	}
+	highlights := []instapaper.Highlight{}
+	for _, h := range s.highlights {
+		if h.BookmarkID == b.BookmarkID {
+			highlights = append(highlights, h)
+		}
+	}
+	writeJSON(w, highlights)
+}
+
+func (s *Server) createHighlight(w http.ResponseWriter, r *http.Request) {
+	b, ok := s.pathBookmark(w, r)
+	if !ok {
+		return
+	}
+	text := r.Form.Get("text")
+	if text == "" {
+		writeError(w, codeEmptyHighlight, "Cannot create highlight with empty text")
+		return
+	}
+	position, _ := strconv.Atoi(r.Form.Get("position"))
+	for _, h := range s.highlights {
+		if h.BookmarkID == b.BookmarkID && h.Text == text && h.Position == position {
+			writeError(w, codeDuplicateHighlight, "Duplicate highlight")
+			return
+		}
+	}
+	s.nextID++
+	highlight := instapaper.Highlight{
+		HighlightID: s.nextID,
+		BookmarkID:  b.BookmarkID,
+		Text:        text,
+		Position:    position,
+		Time:        time.Now().Unix(),
+		Type:        "highlight",
+	}
+	s.highlights = append(s.highlights, highlight)
+	writeJSON(w, []instapaper.Highlight{highlight})
+}
+
+func (s *Server) deleteHighlight(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err == nil {
+		for i, h := range s.highlights {
+			if h.HighlightID == id {
+				s.highlights = append(s.highlights[:i], s.highlights[i+1:]...)
+				writeJSON(w, []any{})
+				return
+			}
+		}
+	}
+	http.NotFound(w, r)
+}
+
+// misc helper functions
+
+// formBookmark returns the bookmark in the bookmark_id parameter, or writes an error
+func (s *Server) formBookmark(w http.ResponseWriter, r *http.Request) (*bookmark, bool) {
+	return s.lookupBookmark(w, r.Form.Get("bookmark_id"))
+}
+
+// pathBookmark returns the bookmark in the {id} path segment, or writes an error
+func (s *Server) pathBookmark(w http.ResponseWriter, r *http.Request) (*bookmark, bool) {
+	return s.lookupBookmark(w, r.PathValue("id"))
+}
+
+func (s *Server) lookupBookmark(w http.ResponseWriter, rawID string) (*bookmark, bool) {
+	id, err := strconv.ParseInt(rawID, 10, 64)
+	b, ok := s.bookmarks[id]
+	if err != nil || !ok {
+		writeError(w, codeInvalidBookmarkID, "Invalid or missing bookmark_id")
+		return nil, false
+	}
+	return b, true
+}
+
+func (s *Server) folderExists(folderID string) bool {
+	switch folderID {
+	case instapaper.FolderUnread, instapaper.FolderStarred, instapaper.FolderArchive:
+		return true
+	}
+	return s.folderIndex(folderID) >= 0
+}
+
+// folderIndex returns the index of a user folder, or -1
+func (s *Server) folderIndex(folderID string) int {
+	for i, f := range s.folders {
+		if f.ID() == folderID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Server) sortedFolders() []instapaper.Folder {
+	folders := append([]instapaper.Folder{}, s.folders...)
+	sort.SliceStable(folders, func(i, j int) bool {
+		return folders[i].Position < folders[j].Position
+	})
+	return folders
+}
+
+// sortedBookmarks returns the bookmarks newest first
+func (s *Server) sortedBookmarks() []*bookmark {
+	bookmarks := []*bookmark{}
+	for _, b := range s.bookmarks {
+		bookmarks = append(bookmarks, b)
+	}
+	sort.Slice(bookmarks, func(i, j int) bool {
+		if bookmarks[i].Time != bookmarks[j].Time {
+			return bookmarks[i].Time > bookmarks[j].Time
+		}
+		return bookmarks[i].BookmarkID > bookmarks[j].BookmarkID
+	})
+	return bookmarks
+}
+
+// tag returns the tag with the name, creating it on first use
+func (s *Server) tag(name string) instapaper.Tag {
+	t, ok := s.tags[name]
+	if !ok {
+		t = instapaper.Tag{ID: len(s.tags) + 1, Name: name, Slug: slug(name)}
+		s.tags[name] = t
+	}
+	return t
+}
+
+func (b *bookmark) inFolder(folderID string) bool {
+	if folderID == instapaper.FolderStarred {
+		return b.Starred == "1"
+	}
+	return b.folder == folderID
+}
+
+// rehash updates the hash after a change, so clients that pass it in have get the bookmark again
+func (b *bookmark) rehash() {
+	h := sha1.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00%s", b.URL, b.Title, b.Description, b.Starred, b.folder)
+	for _, t := range b.Tags {
+		fmt.Fprintf(h, "\x00%s", t.Name)
+	}
+	b.Hash = hex.EncodeToString(h.Sum(nil))[:8]
+}
+
+func formatProgress(progress float64) string {
+	return strconv.FormatFloat(progress, 'f', -1, 64)
+}
+
+func slug(title string) string {
+	return strings.ReplaceAll(strings.ToLower(title), " ", "-")
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeError writes an error object the way the API does
+func writeError(w http.ResponseWriter, code int, message string) {
+	writeErrorStatus(w, http.StatusBadRequest, code, message)
+}
+
+func writeErrorStatus(w http.ResponseWriter, status, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode([]map[string]any{{
+		"type":       "error",
+		"error_code": code,
+		"message":    message,
+	}})
+}
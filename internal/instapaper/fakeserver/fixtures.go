@@ -0,0 +1,105 @@
+// seeded fixtures
+package fakeserver
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/ieroNo47/gopaper/internal/instapaper"
+)
+
+// IDs of the seeded data
+const (
+	FolderTech    int64 = 100
+	FolderRecipes int64 = 101
+
+	BookmarkSpec      int64 = 1
+	BookmarkBubbleTea int64 = 2
+	BookmarkOAuth     int64 = 3
+	BookmarkSourdough int64 = 4
+	BookmarkArchived  int64 = 5
+	BookmarkNoText    int64 = 6
+
+	HighlightSpec int64 = 500
+)
+
+// seedTime is the save time of the newest bookmark, the others are older
+var seedTime = time.Date(2024, time.March, 1, 9, 0, 0, 0, time.UTC)
+
+func (s *Server) seed() {
+	s.user = instapaper.User{
+		Username:             Username,
+		UserID:               42,
+		Type:                 "user",
+		SubscriptionIsActive: "1",
+	}
+	s.folders = []instapaper.Folder{
+		{FolderID: FolderTech, Title: "Tech", DisplayTitle: "Tech", Slug: "tech", SyncToMobile: 1, Position: 1, Type: "folder"},
+		{FolderID: FolderRecipes, Title: "Recipes", DisplayTitle: "Recipes", Slug: "recipes", SyncToMobile: 1, Position: 2, Type: "folder"},
+	}
+
+	s.tags = map[string]instapaper.Tag{}
+	goTag, tuiTag, cookingTag := s.tag("go"), s.tag("tui"), s.tag("cooking")
+	add := func(id int64, age time.Duration, folder, url, title, description string, tags []instapaper.Tag, text string) *bookmark {
+		b := &bookmark{
+			Bookmark: instapaper.Bookmark{
+				BookmarkID:  id,
+				URL:         url,
+				Title:       title,
+				Description: description,
+				Tags:        tags,
+				Time:        seedTime.Add(-age).Unix(),
+				Starred:     "0",
+				Type:        "bookmark",
+			},
+			folder: folder,
+			text:   text,
+		}
+		s.bookmarks[id] = b
+		return b
+	}
+
+	s.bookmarks = map[int64]*bookmark{}
+	spec := add(BookmarkSpec, 0, instapaper.FolderUnread,
+		"https://go.dev/ref/spec", "The Go Programming Language Specification",
+		"The reference manual for Go.", []instapaper.Tag{goTag},
+		"<html><body><h1>The Go Programming Language Specification</h1>"+
+			"<p>Go is a general-purpose language designed with systems programming in mind.</p>"+
+			"<p>It is strongly typed and garbage-collected and has explicit support for concurrent programming.</p>"+
+			"</body></html>")
+	spec.Progress = 0.25
+	spec.ProgressTimestamp = seedTime.Unix()
+	bubbleTea := add(BookmarkBubbleTea, time.Hour, instapaper.FolderUnread,
+		"https://github.com/charmbracelet/bubbletea", "Bubble Tea",
+		"A powerful little TUI framework.", []instapaper.Tag{goTag, tuiTag},
+		"<html><body><h1>Bubble Tea</h1><p>The fun, functional and stateful way to build terminal apps.</p></body></html>")
+	bubbleTea.Starred = "1"
+	add(BookmarkOAuth, 2*time.Hour, instapaper.FolderUnread,
+		"https://oauth.net/core/1.0a/", "OAuth Core 1.0 Revision A",
+		"", []instapaper.Tag{},
+		"<html><body><h1>OAuth Core 1.0a</h1><p>The OAuth protocol enables websites or applications to access protected resources.</p></body></html>")
+	add(BookmarkSourdough, 24*time.Hour, strconv.FormatInt(FolderRecipes, 10),
+		"https://example.com/sourdough", "Sourdough starter guide",
+		"Flour, water and patience.", []instapaper.Tag{cookingTag},
+		"<html><body><h1>Sourdough starter guide</h1><p>Mix equal parts flour and water.</p></body></html>")
+	archived := add(BookmarkArchived, 48*time.Hour, instapaper.FolderArchive,
+		"https://example.com/finished", "A finished article",
+		"", []instapaper.Tag{},
+		"<html><body><p>You read this one already.</p></body></html>")
+	archived.Progress = 1
+	archived.ProgressTimestamp = seedTime.Add(-time.Hour).Unix()
+	add(BookmarkNoText, 72*time.Hour, strconv.FormatInt(FolderTech, 10),
+		"https://example.com/video", "A video without text",
+		"", []instapaper.Tag{goTag}, "")
+	for _, b := range s.bookmarks {
+		b.rehash()
+	}
+
+	s.highlights = []instapaper.Highlight{{
+		HighlightID: HighlightSpec,
+		BookmarkID:  BookmarkSpec,
+		Text:        "Go is a general-purpose language designed with systems programming in mind.",
+		Time:        seedTime.Unix(),
+		Type:        "highlight",
+	}}
+}
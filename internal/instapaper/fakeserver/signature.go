@@ -0,0 +1,94 @@
+// OAuth 1.0a signature verification
+package fakeserver
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// parseAuthorization returns the parameters of an OAuth Authorization header.
+// Values may be quoted and percent-encoded (RFC 5849) or bare like the xauth package sends them.
+func parseAuthorization(header string) url.Values {
+	params := url.Values{}
+	header, ok := strings.CutPrefix(header, "OAuth ")
+	if !ok {
+		return params
+	}
+	for _, pair := range strings.Split(header, ",") {
+		k, v, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found {
+			continue
+		}
+		v = strings.Trim(v, `"`)
+		if unescaped, err := url.PathUnescape(v); err == nil {
+			v = unescaped
+		}
+		params.Set(k, v)
+	}
+	return params
+}
+
+// validSignature checks the HMAC-SHA1 signature of a request. The signed parameters are
+// the OAuth parameters and the form, the key is the consumer secret and the token secret.
+func validSignature(r *http.Request, oauthParams url.Values, tokenSecret string) bool {
+	if oauthParams.Get("oauth_consumer_key") != ConsumerKey ||
+		oauthParams.Get("oauth_signature_method") != "HMAC-SHA1" {
+		return false
+	}
+	signature, err := base64.StdEncoding.DecodeString(oauthParams.Get("oauth_signature"))
+	if err != nil {
+		return false
+	}
+
+	params := []string{}
+	for k, vs := range oauthParams {
+		if k == "oauth_signature" || k == "realm" {
+			continue
+		}
+		for _, v := range vs {
+			params = append(params, percentEncode(k)+"="+percentEncode(v))
+		}
+	}
+	for k, vs := range r.Form {
+		for _, v := range vs {
+			params = append(params, percentEncode(k)+"="+percentEncode(v))
+		}
+	}
+	sort.Strings(params)
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	baseURL := fmt.Sprintf("%s://%s%s", scheme, strings.ToLower(r.Host), r.URL.EscapedPath())
+	base := strings.Join([]string{
+		r.Method,
+		percentEncode(baseURL),
+		percentEncode(strings.Join(params, "&")),
+	}, "&")
+
+	mac := hmac.New(sha1.New, []byte(percentEncode(ConsumerSecret)+"&"+percentEncode(tokenSecret)))
+	mac.Write([]byte(base))
+	return hmac.Equal(mac.Sum(nil), signature)
+}
+
+// percentEncode encodes a string as specified by RFC 3986, section 2.1
+func percentEncode(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -2,12 +2,14 @@
 package instapaper
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -17,16 +19,78 @@ import (
 )
 
 const (
-	defaultTimeout   = 10 * time.Second
-	contentType      = "application/x-www-form-urlencoded"
+	contentType = "application/x-www-form-urlencoded"
+
+	accountVerifyCredentials = "account/verify_credentials"
+
 	bookmarksList    = "bookmarks/list"
 	bookmarksGetText = "bookmarks/get_text"
+
+	bookmarksAdd                = "bookmarks/add"
+	bookmarksDelete             = "bookmarks/delete"
+	bookmarksStar               = "bookmarks/star"
+	bookmarksUnstar             = "bookmarks/unstar"
+	bookmarksArchive            = "bookmarks/archive"
+	bookmarksUnarchive          = "bookmarks/unarchive"
+	bookmarksMove               = "bookmarks/move"
+	bookmarksUpdateReadProgress = "bookmarks/update_read_progress"
+
+	foldersList     = "folders/list"
+	foldersAdd      = "folders/add"
+	foldersDelete   = "folders/delete"
+	foldersSetOrder = "folders/set_order"
+
+	// highlight endpoints take the bookmark or highlight ID as part of the path
+	bookmarkHighlights = "bookmarks/%d/highlights"
+	bookmarkHighlight  = "bookmarks/%d/highlight"
+	highlightDelete    = "highlights/%d/delete"
+)
+
+// special folder IDs accepted by bookmarks/list
+const (
+	FolderUnread  = "unread"
+	FolderStarred = "starred"
+	FolderArchive = "archive"
 )
 
+// MaxListLimit is the most bookmarks bookmarks/list returns at once
+const MaxListLimit = 500
+
 type Response struct {
 	Highlights []Highlight `json:"highlights"` // empty array
 	Bookmarks  []Bookmark  `json:"bookmarks"`
 	User       User        `json:"user"`
+	// DeleteIDs are the bookmarks passed in ListOptions.Have that are no longer in the folder
+	DeleteIDs IDList `json:"delete_ids"`
+}
+
+// IDList is a list of bookmark IDs. The API sends it as a comma-separated string.
+type IDList []int64
+
+func (l *IDList) UnmarshalJSON(data []byte) error {
+	// accept both "1,2,3" and [1,2,3]
+	var ids []int64
+	if err := json.Unmarshal(data, &ids); err == nil {
+		*l = ids
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("invalid id list %s: %w", string(data), err)
+	}
+	*l = IDList{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid id list %q: %w", s, err)
+		}
+		*l = append(*l, id)
+	}
+	return nil
 }
 
 type Highlight struct {
@@ -54,6 +118,38 @@ type Bookmark struct {
 	Type              string  `json:"type"`
 }
 
+// Have returns the state of the bookmark as known to the client
+func (b Bookmark) Have() Have {
+	return Have{
+		BookmarkID:        b.BookmarkID,
+		Hash:              b.Hash,
+		Progress:          b.Progress,
+		ProgressTimestamp: b.ProgressTimestamp,
+	}
+}
+
+// Have is a bookmark the client already has. bookmarks/list only returns it again
+// if the hash or the read progress changed on the server.
+type Have struct {
+	BookmarkID        int64
+	Hash              string
+	Progress          float64
+	ProgressTimestamp int64
+}
+
+// String formats the bookmark as id:hash:progress:progress_timestamp,
+// or just the id if there is no hash
+func (h Have) String() string {
+	if h.Hash == "" {
+		return strconv.FormatInt(h.BookmarkID, 10)
+	}
+	return fmt.Sprintf("%d:%s:%s:%d",
+		h.BookmarkID,
+		h.Hash,
+		strconv.FormatFloat(h.Progress, 'f', -1, 64),
+		h.ProgressTimestamp)
+}
+
 type Tag struct {
 	Count int     `json:"count"`
 	Hash  string  `json:"hash"`
@@ -63,6 +159,28 @@ type Tag struct {
 	Slug  string  `json:"slug"`
 }
 
+type Folder struct {
+	FolderID     int64   `json:"folder_id"`
+	Title        string  `json:"title"`
+	Slug         string  `json:"slug"`
+	DisplayTitle string  `json:"display_title"`
+	SyncToMobile int     `json:"sync_to_mobile"`
+	Position     float64 `json:"position"`
+	Type         string  `json:"type"`
+}
+
+// ID returns the folder ID in the form used by bookmarks/list
+func (f Folder) ID() string {
+	return strconv.FormatInt(f.FolderID, 10)
+}
+
+// responseItem is used to peek at the type of an element in an API response
+type responseItem struct {
+	Type string `json:"type"`
+	APIError
+}
+
+// User is the account of the access token. SubscriptionIsActive is "1" for Instapaper Premium.
 type User struct {
 	Username             string `json:"username"`
 	UserID               int    `json:"user_id"`
@@ -77,61 +195,168 @@ type Client struct {
 	baseURL    string
 }
 
-func NewClient() (Client, error) {
-	tokenValues, err := xauth.GetToken()
+// Token is an OAuth access token obtained with Login
+type Token struct {
+	Token  string `json:"oauth_token"`
+	Secret string `json:"oauth_token_secret"`
+}
+
+// Login exchanges the username and password for an access token using xAuth.
+// The password is only needed once, the token can be stored and reused with WithToken.
+func Login(ctx context.Context, username, password string, opts ...Option) (Token, error) {
+	cfg := newConfig(opts)
+	if err := cfg.validate(); err != nil {
+		return Token{}, err
+	}
+	httpClient := cfg.httpClient()
+	httpClient.Transport = newRetryTransport(httpClient.Transport, cfg)
+	tokenValues, err := xauth.GetToken(ctx, xauth.Config{
+		AccessTokenURL: cfg.endpointURL(xauth.AccessTokenPath),
+		ConsumerKey:    cfg.ConsumerKey,
+		ConsumerSecret: cfg.ConsumerSecret,
+		HTTPClient:     httpClient,
+	}, username, password)
 	if err != nil {
-		return Client{}, fmt.Errorf("failed to get token: %w", err)
+		return Token{}, fmt.Errorf("failed to get token: %w", err)
 	}
+	return Token{
+		Token:  tokenValues.Get("oauth_token"),
+		Secret: tokenValues.Get("oauth_token_secret"),
+	}, nil
+}
 
-	tokenSecret := tokenValues.Get("oauth_token_secret")
-	token := tokenValues.Get("oauth_token")
-	config := oauth1.NewConfig(
-		os.Getenv("IP_OAUTH_CONSUMER_ID"),
-		os.Getenv("IP_OAUTH_CONSUMER_SECRET"))
-	otoken := oauth1.NewToken(token, tokenSecret)
-	httpClient := config.Client(oauth1.NoContext, otoken)
-	httpClient.Timeout = defaultTimeout
-	return Client{httpClient: httpClient,
-		apiVersion: os.Getenv("IP_API_VERSION"),
-		baseURL:    os.Getenv("IP_API")}, nil
+// NewClient creates a client from the options. The consumer credentials are required,
+// see WithCredentials and WithEnv, and a token is needed for everything but Login.
+func NewClient(opts ...Option) (Client, error) {
+	return New(newConfig(opts))
 }
 
-func (c Client) GetBookmarks(limit int) ([]Bookmark, error) {
-	bookmarksURL := fmt.Sprintf("%s/%s/%s",
-		c.baseURL,
-		c.apiVersion,
-		bookmarksList)
-	values := url.Values{}
-	values.Add("limit", strconv.Itoa(limit))
+// New creates a client from an explicit configuration
+func New(cfg Config) (Client, error) {
+	cfg = cfg.withDefaults()
+	if err := cfg.validate(); err != nil {
+		return Client{}, err
+	}
+	base := cfg.httpClient()
+	// the oauth1 transport signs requests and hands them to the transport of the client in the context
+	ctx := context.WithValue(context.Background(), oauth1.HTTPClient, base)
+	oauthConfig := oauth1.NewConfig(cfg.ConsumerKey, cfg.ConsumerSecret)
+	signed := oauthConfig.Client(ctx, oauth1.NewToken(cfg.Token.Token, cfg.Token.Secret))
+	httpClient := *base
+	// every retry is signed again
+	httpClient.Transport = newRetryTransport(signed.Transport, cfg)
+	return Client{httpClient: &httpClient,
+		apiVersion: cfg.APIVersion,
+		baseURL:    cfg.BaseURL}, nil
+}
 
-	resp, err := c.httpClient.Post(bookmarksURL,
-		contentType,
-		strings.NewReader(values.Encode()),
-	)
+// VerifyCredentials returns the user the access token belongs to
+func (c Client) VerifyCredentials(ctx context.Context) (User, error) {
+	body, err := c.post(ctx, accountVerifyCredentials, url.Values{})
 	if err != nil {
-		return nil, err
+		return User{}, err
 	}
-	defer resp.Body.Close()
+	items, err := decodeItems(body, "user")
+	if err != nil {
+		return User{}, fmt.Errorf("failed to decode %s response: %w", accountVerifyCredentials, err)
+	}
+	if len(items) == 0 {
+		return User{}, fmt.Errorf("no user in %s response", accountVerifyCredentials)
+	}
+	var user User
+	if err := json.Unmarshal(items[0], &user); err != nil {
+		return User{}, fmt.Errorf("failed to decode %s response: %w", accountVerifyCredentials, err)
+	}
+	return user, nil
+}
 
-	if resp.StatusCode != 200 {
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read body")
+// ListOptions are the parameters for bookmarks/list
+type ListOptions struct {
+	Limit int
+	// FolderID is one of FolderUnread, FolderStarred, FolderArchive or the ID of a user-created folder.
+	// Defaults to FolderUnread when empty.
+	FolderID string
+	// Have lists the bookmarks the client already has, see Have.
+	Have []Have
+}
+
+func (c Client) ListBookmarks(ctx context.Context, opts ListOptions) (Response, error) {
+	values := url.Values{}
+	values.Add("limit", strconv.Itoa(opts.Limit))
+	if opts.FolderID != "" {
+		values.Add("folder_id", opts.FolderID)
+	}
+	if len(opts.Have) > 0 {
+		have := []string{}
+		for _, h := range opts.Have {
+			have = append(have, h.String())
 		}
-		return nil, fmt.Errorf("failed to get bookmarks list: code: %d, body: %s", resp.StatusCode, string(body))
+		values.Add("have", strings.Join(have, ","))
+	}
+
+	body, err := c.post(ctx, bookmarksList, values)
+	if err != nil {
+		return Response{}, err
 	}
 
 	// parse json response
 	var response Response
-	err = json.NewDecoder(resp.Body).Decode(&response)
+	if err := json.Unmarshal(body, &response); err != nil {
+		return Response{}, fmt.Errorf("failed to decode %s response: %w", bookmarksList, err)
+	}
+	return response, nil
+}
+
+// ListAllBookmarks lists every bookmark in a folder, not just the first opts.Limit.
+// opts.Limit is the page size, MaxListLimit by default. Each page excludes the bookmarks
+// of the previous ones by adding them to the have parameter.
+func (c Client) ListAllBookmarks(ctx context.Context, opts ListOptions) (Response, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = MaxListLimit
+	}
+	// the position of each bookmark in have, a changed bookmark replaces its entry
+	have := map[int64]int{}
+	opts.Have = slices.Clone(opts.Have)
+	for i, h := range opts.Have {
+		have[h.BookmarkID] = i
+	}
+	all := Response{Bookmarks: []Bookmark{}, Highlights: []Highlight{}}
+	for page := 0; ; page++ {
+		response, err := c.ListBookmarks(ctx, opts)
+		if err != nil {
+			return Response{}, err
+		}
+		if page == 0 {
+			// later pages report the same deleted bookmarks
+			all.User = response.User
+			all.DeleteIDs = response.DeleteIDs
+		}
+		all.Bookmarks = append(all.Bookmarks, response.Bookmarks...)
+		all.Highlights = append(all.Highlights, response.Highlights...)
+		if len(response.Bookmarks) < opts.Limit {
+			return all, nil
+		}
+		for _, b := range response.Bookmarks {
+			if i, ok := have[b.BookmarkID]; ok {
+				opts.Have[i] = b.Have()
+				continue
+			}
+			have[b.BookmarkID] = len(opts.Have)
+			opts.Have = append(opts.Have, b.Have())
+		}
+	}
+}
+
+func (c Client) GetBookmarks(ctx context.Context, limit int) ([]Bookmark, error) {
+	response, err := c.ListBookmarks(ctx, ListOptions{Limit: limit})
 	if err != nil {
 		return nil, err
 	}
 	return response.Bookmarks, nil
 }
 
-func (c Client) GetBookmarkTitles(limit int) ([]string, error) {
-	bookmarks, err := c.GetBookmarks(limit)
+func (c Client) GetBookmarkTitles(ctx context.Context, limit int) ([]string, error) {
+	bookmarks, err := c.GetBookmarks(ctx, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -142,30 +367,312 @@ func (c Client) GetBookmarkTitles(limit int) ([]string, error) {
 	return titles, nil
 }
 
-func (c Client) GetBookmarkText(bookmarkID int64) (string, error) {
-	bookmarksURL := fmt.Sprintf("%s/%s/%s",
+// GetBookmarkText returns the processed text of a bookmark as HTML.
+// Fails with ErrTextUnavailable if Instapaper could not extract the text.
+func (c Client) GetBookmarkText(ctx context.Context, bookmarkID int64) (string, error) {
+	body, err := c.post(ctx, bookmarksGetText, bookmarkValues(bookmarkID))
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// AddBookmarkParams are the parameters for bookmarks/add. Only URL is required,
+// unless the bookmark is private.
+type AddBookmarkParams struct {
+	URL         string
+	Title       string
+	Description string
+	FolderID    int64
+	// Tags replace the tags of the bookmark if not empty, see SetBookmarkTags to remove all tags
+	Tags []string
+	// Content is the HTML of the page, for pages Instapaper cannot fetch itself
+	Content string
+	// PrivateSource saves Content as a private bookmark without a public URL. It describes
+	// where the content is from, e.g. "email", and is sent as is_private_from_source.
+	PrivateSource string
+}
+
+// AddBookmark saves a new bookmark, or updates the title and description
+// of an existing bookmark with the same URL
+func (c Client) AddBookmark(ctx context.Context, params AddBookmarkParams) (Bookmark, error) {
+	values := url.Values{}
+	if params.URL != "" {
+		values.Add("url", params.URL)
+	}
+	if params.Title != "" {
+		values.Add("title", params.Title)
+	}
+	if params.Description != "" {
+		values.Add("description", params.Description)
+	}
+	if params.FolderID != 0 {
+		values.Add("folder_id", strconv.FormatInt(params.FolderID, 10))
+	}
+	if len(params.Tags) > 0 {
+		values.Add("tags", tagsValue(params.Tags))
+	}
+	if params.Content != "" {
+		values.Add("content", params.Content)
+	}
+	if params.PrivateSource != "" {
+		values.Add("is_private_from_source", params.PrivateSource)
+	}
+	return c.postBookmark(ctx, bookmarksAdd, values)
+}
+
+// DeleteBookmark permanently deletes a bookmark
+func (c Client) DeleteBookmark(ctx context.Context, bookmarkID int64) error {
+	_, err := c.post(ctx, bookmarksDelete, bookmarkValues(bookmarkID))
+	return err
+}
+
+func (c Client) StarBookmark(ctx context.Context, bookmarkID int64) (Bookmark, error) {
+	return c.postBookmark(ctx, bookmarksStar, bookmarkValues(bookmarkID))
+}
+
+func (c Client) UnstarBookmark(ctx context.Context, bookmarkID int64) (Bookmark, error) {
+	return c.postBookmark(ctx, bookmarksUnstar, bookmarkValues(bookmarkID))
+}
+
+func (c Client) ArchiveBookmark(ctx context.Context, bookmarkID int64) (Bookmark, error) {
+	return c.postBookmark(ctx, bookmarksArchive, bookmarkValues(bookmarkID))
+}
+
+func (c Client) UnarchiveBookmark(ctx context.Context, bookmarkID int64) (Bookmark, error) {
+	return c.postBookmark(ctx, bookmarksUnarchive, bookmarkValues(bookmarkID))
+}
+
+// MoveBookmark moves a bookmark to a user-created folder
+func (c Client) MoveBookmark(ctx context.Context, bookmarkID int64, folderID int64) (Bookmark, error) {
+	values := bookmarkValues(bookmarkID)
+	values.Add("folder_id", strconv.FormatInt(folderID, 10))
+	return c.postBookmark(ctx, bookmarksMove, values)
+}
+
+// UpdateReadProgress sets the read progress of a bookmark. progress is a value between 0.0 and 1.0,
+// timestamp is the time the progress was recorded, used by the API to resolve conflicts between clients.
+func (c Client) UpdateReadProgress(ctx context.Context, bookmarkID int64, progress float64, timestamp time.Time) (Bookmark, error) {
+	if progress < 0 || progress > 1 {
+		return Bookmark{}, fmt.Errorf("invalid progress %.2f: must be between 0 and 1", progress)
+	}
+	values := bookmarkValues(bookmarkID)
+	values.Add("progress", strconv.FormatFloat(progress, 'f', -1, 64))
+	values.Add("progress_timestamp", strconv.FormatInt(timestamp.Unix(), 10))
+	return c.postBookmark(ctx, bookmarksUpdateReadProgress, values)
+}
+
+func (c Client) ListFolders(ctx context.Context) ([]Folder, error) {
+	body, err := c.post(ctx, foldersList, url.Values{})
+	if err != nil {
+		return nil, err
+	}
+	return decodeFolders(body)
+}
+
+// AddFolder creates a folder. The API returns error 1251 if a folder with the same title already exists.
+func (c Client) AddFolder(ctx context.Context, title string) (Folder, error) {
+	values := url.Values{}
+	values.Add("title", title)
+	body, err := c.post(ctx, foldersAdd, values)
+	if err != nil {
+		return Folder{}, err
+	}
+	folders, err := decodeFolders(body)
+	if err != nil {
+		return Folder{}, err
+	}
+	if len(folders) == 0 {
+		return Folder{}, fmt.Errorf("no folder in %s response", foldersAdd)
+	}
+	return folders[0], nil
+}
+
+// DeleteFolder deletes a folder and any bookmarks in it
+func (c Client) DeleteFolder(ctx context.Context, folderID int64) error {
+	values := url.Values{}
+	values.Add("folder_id", strconv.FormatInt(folderID, 10))
+	_, err := c.post(ctx, foldersDelete, values)
+	return err
+}
+
+// SetFolderOrder reorders the user-created folders. folderIDs is the new order, first to last.
+func (c Client) SetFolderOrder(ctx context.Context, folderIDs []int64) ([]Folder, error) {
+	order := []string{}
+	for i, id := range folderIDs {
+		order = append(order, fmt.Sprintf("%d:%d", id, i+1))
+	}
+	values := url.Values{}
+	values.Add("order", strings.Join(order, ","))
+	body, err := c.post(ctx, foldersSetOrder, values)
+	if err != nil {
+		return nil, err
+	}
+	return decodeFolders(body)
+}
+
+// ListHighlights returns the highlights of a bookmark
+func (c Client) ListHighlights(ctx context.Context, bookmarkID int64) ([]Highlight, error) {
+	body, err := c.post(ctx, fmt.Sprintf(bookmarkHighlights, bookmarkID), url.Values{})
+	if err != nil {
+		return nil, err
+	}
+	return decodeHighlights(body)
+}
+
+// CreateHighlight highlights text in a bookmark. position is the 0-indexed position
+// of text in the bookmark content, used to tell apart duplicate passages.
+func (c Client) CreateHighlight(ctx context.Context, bookmarkID int64, text string, position int) (Highlight, error) {
+	values := url.Values{}
+	values.Add("text", text)
+	values.Add("position", strconv.Itoa(position))
+	body, err := c.post(ctx, fmt.Sprintf(bookmarkHighlight, bookmarkID), values)
+	if err != nil {
+		return Highlight{}, err
+	}
+	highlights, err := decodeHighlights(body)
+	if err != nil {
+		return Highlight{}, err
+	}
+	if len(highlights) == 0 {
+		return Highlight{}, fmt.Errorf("no highlight in response")
+	}
+	return highlights[0], nil
+}
+
+func (c Client) DeleteHighlight(ctx context.Context, highlightID int64) error {
+	_, err := c.post(ctx, fmt.Sprintf(highlightDelete, highlightID), url.Values{})
+	return err
+}
+
+// misc helper functions
+
+func bookmarkValues(bookmarkID int64) url.Values {
+	values := url.Values{}
+	values.Add("bookmark_id", strconv.FormatInt(bookmarkID, 10))
+	return values
+}
+
+// post sends a signed request to an API endpoint and returns the response body.
+// Failed requests are returned as *APIError.
+func (c Client) post(ctx context.Context, endpoint string, values url.Values) ([]byte, error) {
+	endpointURL := fmt.Sprintf("%s/%s/%s",
 		c.baseURL,
 		c.apiVersion,
-		bookmarksGetText)
-	values := url.Values{}
-	values.Add("bookmark_id", strconv.Itoa(int(bookmarkID)))
+		endpoint)
 
-	resp, err := c.httpClient.Post(bookmarksURL,
-		contentType,
-		strings.NewReader(values.Encode()),
-	)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpointURL, strings.NewReader(values.Encode()))
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read body: %v", err)
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+	if apiErr := newAPIError(resp.StatusCode, body); apiErr != nil {
+		return nil, apiErr
 	}
+	return body, nil
+}
 
-	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("failed to get bookmarks list: %s", string(body))
+// postBookmark calls an endpoint that returns a single bookmark
+func (c Client) postBookmark(ctx context.Context, endpoint string, values url.Values) (Bookmark, error) {
+	body, err := c.post(ctx, endpoint, values)
+	if err != nil {
+		return Bookmark{}, err
 	}
-	return string(body), nil
+	bookmarks, err := decodeBookmarks(body)
+	if err != nil {
+		return Bookmark{}, fmt.Errorf("failed to decode %s response: %w", endpoint, err)
+	}
+	if len(bookmarks) == 0 {
+		return Bookmark{}, fmt.Errorf("no bookmark in %s response", endpoint)
+	}
+	return bookmarks[0], nil
+}
+
+// decodeItems returns the raw objects of the given type from a response body
+// that contains an array of typed objects
+func decodeItems(body []byte, itemType string) ([]json.RawMessage, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, err
+	}
+	items := []json.RawMessage{}
+	for _, r := range raw {
+		var item responseItem
+		if err := json.Unmarshal(r, &item); err != nil {
+			return nil, err
+		}
+		if item.Type == itemType {
+			items = append(items, r)
+		}
+	}
+	return items, nil
+}
+
+// decodeBookmarks decodes the bookmarks in a response body, which is either
+// an array of typed objects or a Response object
+func decodeBookmarks(body []byte) ([]Bookmark, error) {
+	body = bytes.TrimSpace(body)
+	if !bytes.HasPrefix(body, []byte("[")) {
+		var response Response
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, err
+		}
+		return response.Bookmarks, nil
+	}
+
+	items, err := decodeItems(body, "bookmark")
+	if err != nil {
+		return nil, err
+	}
+	bookmarks := []Bookmark{}
+	for _, r := range items {
+		var bookmark Bookmark
+		if err := json.Unmarshal(r, &bookmark); err != nil {
+			return nil, err
+		}
+		bookmarks = append(bookmarks, bookmark)
+	}
+	return bookmarks, nil
+}
+
+func decodeFolders(body []byte) ([]Folder, error) {
+	items, err := decodeItems(body, "folder")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode folders: %w", err)
+	}
+	folders := []Folder{}
+	for _, r := range items {
+		var folder Folder
+		if err := json.Unmarshal(r, &folder); err != nil {
+			return nil, fmt.Errorf("failed to decode folders: %w", err)
+		}
+		folders = append(folders, folder)
+	}
+	return folders, nil
+}
+
+func decodeHighlights(body []byte) ([]Highlight, error) {
+	items, err := decodeItems(body, "highlight")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode highlights: %w", err)
+	}
+	highlights := []Highlight{}
+	for _, r := range items {
+		var highlight Highlight
+		if err := json.Unmarshal(r, &highlight); err != nil {
+			return nil, fmt.Errorf("failed to decode highlights: %w", err)
+		}
+		highlights = append(highlights, highlight)
+	}
+	return highlights, nil
 }
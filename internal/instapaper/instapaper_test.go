@@ -0,0 +1,741 @@
+package instapaper_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ieroNo47/gopaper/internal/instapaper"
+	"github.com/ieroNo47/gopaper/internal/instapaper/fakeserver"
+	"github.com/ieroNo47/gopaper/internal/instapaper/xauth"
+)
+
+// newTestClient starts a fake server and returns a client logged in to it
+func newTestClient(t *testing.T, opts ...instapaper.Option) (*fakeserver.Server, instapaper.Client) {
+	t.Helper()
+	srv := fakeserver.New()
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	opts = append(fakeserver.ClientOptions(ts.URL), append(opts, instapaper.WithToken(srv.IssueToken()))...)
+	client, err := instapaper.NewClient(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, client
+}
+
+func bookmarkIDs(bookmarks []instapaper.Bookmark) []int64 {
+	ids := []int64{}
+	for _, b := range bookmarks {
+		ids = append(ids, b.BookmarkID)
+	}
+	return ids
+}
+
+func tagNames(t *testing.T, srv *fakeserver.Server, bookmarkID int64) []string {
+	t.Helper()
+	b, _, ok := srv.Bookmark(bookmarkID)
+	if !ok {
+		t.Fatalf("no bookmark %d", bookmarkID)
+	}
+	return instapaper.TagNames(b.Tags)
+}
+
+func TestLogin(t *testing.T) {
+	ctx := context.Background()
+	ts := httptest.NewServer(fakeserver.New())
+	defer ts.Close()
+	opts := fakeserver.ClientOptions(ts.URL)
+
+	token, err := instapaper.Login(ctx, fakeserver.Username, fakeserver.Password, opts...)
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if token.Token == "" || token.Secret == "" {
+		t.Fatalf("Login() = %+v, want a token and secret", token)
+	}
+	client, err := instapaper.NewClient(append(opts, instapaper.WithToken(token))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ListFolders(ctx); err != nil {
+		t.Errorf("ListFolders() with the new token error = %v", err)
+	}
+
+	_, err = instapaper.Login(ctx, fakeserver.Username, "wrong password", opts...)
+	if !errors.Is(err, xauth.ErrInvalidCredentials) {
+		t.Errorf("Login() with a wrong password error = %v, want %v", err, xauth.ErrInvalidCredentials)
+	}
+}
+
+func TestVerifyCredentials(t *testing.T) {
+	ctx := context.Background()
+	srv, client := newTestClient(t)
+	user, err := client.VerifyCredentials(ctx)
+	if err != nil {
+		t.Fatalf("VerifyCredentials() error = %v", err)
+	}
+	if user.Username != fakeserver.Username || user.SubscriptionIsActive != "1" {
+		t.Errorf("VerifyCredentials() = %+v, want the premium user %q", user, fakeserver.Username)
+	}
+	srv.RevokeTokens()
+	if _, err := client.VerifyCredentials(ctx); !errors.Is(err, instapaper.ErrUnauthorized) {
+		t.Errorf("VerifyCredentials() with a revoked token error = %v, want %v", err, instapaper.ErrUnauthorized)
+	}
+}
+
+func TestListBookmarks(t *testing.T) {
+	ctx := context.Background()
+	_, client := newTestClient(t)
+	tests := []struct {
+		folderID string
+		want     []int64
+	}{
+		{"", []int64{fakeserver.BookmarkSpec, fakeserver.BookmarkBubbleTea, fakeserver.BookmarkOAuth}},
+		{instapaper.FolderStarred, []int64{fakeserver.BookmarkBubbleTea}},
+		{instapaper.FolderArchive, []int64{fakeserver.BookmarkArchived}},
+		{strconv.FormatInt(fakeserver.FolderRecipes, 10), []int64{fakeserver.BookmarkSourdough}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.folderID, func(t *testing.T) {
+			response, err := client.ListBookmarks(ctx, instapaper.ListOptions{Limit: 10, FolderID: tt.folderID})
+			if err != nil {
+				t.Fatalf("ListBookmarks() error = %v", err)
+			}
+			if got := bookmarkIDs(response.Bookmarks); !slices.Equal(got, tt.want) {
+				t.Errorf("ListBookmarks() bookmarks = %v, want %v", got, tt.want)
+			}
+			if response.User.Username != fakeserver.Username {
+				t.Errorf("ListBookmarks() user = %q, want %q", response.User.Username, fakeserver.Username)
+			}
+		})
+	}
+
+	response, err := client.ListBookmarks(ctx, instapaper.ListOptions{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Bookmarks) != 1 {
+		t.Errorf("ListBookmarks() with limit 1 returned %d bookmarks", len(response.Bookmarks))
+	}
+
+	if _, err := client.ListBookmarks(ctx, instapaper.ListOptions{FolderID: "12345"}); err == nil {
+		t.Error("ListBookmarks() with an unknown folder succeeded")
+	}
+}
+
+func TestListBookmarksHave(t *testing.T) {
+	ctx := context.Background()
+	_, client := newTestClient(t)
+	response, err := client.ListBookmarks(ctx, instapaper.ListOptions{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	have := []instapaper.Have{}
+	for _, b := range response.Bookmarks {
+		have = append(have, b.Have())
+	}
+	// a bookmark the client has but that is no longer in the folder
+	have = append(have, instapaper.Have{BookmarkID: fakeserver.BookmarkArchived})
+
+	if _, err := client.UpdateReadProgress(ctx, fakeserver.BookmarkOAuth, 0.5, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	response, err = client.ListBookmarks(ctx, instapaper.ListOptions{Limit: 10, Have: have})
+	if err != nil {
+		t.Fatalf("ListBookmarks() error = %v", err)
+	}
+	if got, want := bookmarkIDs(response.Bookmarks), []int64{fakeserver.BookmarkOAuth}; !slices.Equal(got, want) {
+		t.Errorf("ListBookmarks() bookmarks = %v, want only the changed %v", got, want)
+	}
+	if got, want := []int64(response.DeleteIDs), []int64{fakeserver.BookmarkArchived}; !slices.Equal(got, want) {
+		t.Errorf("ListBookmarks() delete_ids = %v, want %v", got, want)
+	}
+}
+
+func TestLibrarySync(t *testing.T) {
+	ctx := context.Background()
+	_, client := newTestClient(t)
+	library := instapaper.NewLibrary(client, instapaper.FolderUnread, 10, nil)
+	result, err := library.Sync(ctx)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if len(result.Added) != 3 {
+		t.Errorf("first Sync() added %d bookmarks, want 3", len(result.Added))
+	}
+
+	if _, err := client.ArchiveBookmark(ctx, fakeserver.BookmarkSpec); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.StarBookmark(ctx, fakeserver.BookmarkOAuth); err != nil {
+		t.Fatal(err)
+	}
+	result, err = library.Sync(ctx)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if !slices.Equal(result.Deleted, []int64{fakeserver.BookmarkSpec}) {
+		t.Errorf("Sync() deleted = %v, want [%d]", result.Deleted, fakeserver.BookmarkSpec)
+	}
+	if len(result.Updated) != 1 || result.Updated[0].BookmarkID != fakeserver.BookmarkOAuth {
+		t.Errorf("Sync() updated = %v, want bookmark %d", bookmarkIDs(result.Updated), fakeserver.BookmarkOAuth)
+	}
+	if got, want := bookmarkIDs(library.Bookmarks()), []int64{fakeserver.BookmarkBubbleTea, fakeserver.BookmarkOAuth}; !slices.Equal(got, want) {
+		t.Errorf("Bookmarks() = %v, want %v", got, want)
+	}
+}
+
+func TestLibraryPages(t *testing.T) {
+	ctx := context.Background()
+	_, client := newTestClient(t)
+	library := instapaper.NewLibrary(client, instapaper.FolderUnread, 2, nil)
+	for _, want := range []int{2, 1} {
+		result, err := library.Sync(ctx)
+		if err != nil {
+			t.Fatalf("Sync() error = %v", err)
+		}
+		if len(result.Added) != want {
+			t.Errorf("Sync() added %d bookmarks, want the next %d", len(result.Added), want)
+		}
+	}
+	if !library.Complete() || len(library.Bookmarks()) != 3 {
+		t.Errorf("library has %d bookmarks, complete %v, want all 3", len(library.Bookmarks()), library.Complete())
+	}
+}
+
+func TestListAllBookmarks(t *testing.T) {
+	ctx := context.Background()
+	srv, client := newTestClient(t)
+	spec, _, _ := srv.Bookmark(fakeserver.BookmarkSpec)
+	requests := srv.Requests()
+	response, err := client.ListAllBookmarks(ctx, instapaper.ListOptions{Limit: 1, Have: []instapaper.Have{spec.Have()}})
+	if err != nil {
+		t.Fatalf("ListAllBookmarks() error = %v", err)
+	}
+	if got, want := bookmarkIDs(response.Bookmarks), []int64{fakeserver.BookmarkBubbleTea, fakeserver.BookmarkOAuth}; !slices.Equal(got, want) {
+		t.Errorf("ListAllBookmarks() = %v, want %v", got, want)
+	}
+	// the last page is empty
+	if n := srv.Requests() - requests; n != 3 {
+		t.Errorf("ListAllBookmarks() sent %d requests, want 3 pages", n)
+	}
+}
+
+func TestGetBookmarkText(t *testing.T) {
+	ctx := context.Background()
+	_, client := newTestClient(t)
+	text, err := client.GetBookmarkText(ctx, fakeserver.BookmarkSpec)
+	if err != nil {
+		t.Fatalf("GetBookmarkText() error = %v", err)
+	}
+	if !strings.Contains(text, "general-purpose language") {
+		t.Errorf("GetBookmarkText() = %q, want the article", text)
+	}
+	if _, err := client.GetBookmarkText(ctx, fakeserver.BookmarkNoText); err == nil {
+		t.Error("GetBookmarkText() of a bookmark without text succeeded")
+	}
+}
+
+func TestBookmarkChanges(t *testing.T) {
+	ctx := context.Background()
+	srv, client := newTestClient(t)
+
+	added, err := client.AddBookmark(ctx, instapaper.AddBookmarkParams{
+		URL:      "https://example.com/new",
+		Title:    "New",
+		FolderID: fakeserver.FolderTech,
+	})
+	if err != nil {
+		t.Fatalf("AddBookmark() error = %v", err)
+	}
+	if _, folder, ok := srv.Bookmark(added.BookmarkID); !ok || folder != strconv.FormatInt(fakeserver.FolderTech, 10) {
+		t.Errorf("added bookmark is in folder %q, want %d", folder, fakeserver.FolderTech)
+	}
+	var apiErr *instapaper.APIError
+	if _, err := client.AddBookmark(ctx, instapaper.AddBookmarkParams{URL: "not a url"}); !errors.As(err, &apiErr) || apiErr.Code != 1240 {
+		t.Errorf("AddBookmark() with an invalid URL error = %v, want error 1240", err)
+	}
+
+	starred, err := client.StarBookmark(ctx, added.BookmarkID)
+	if err != nil || starred.Starred != "1" {
+		t.Errorf("StarBookmark() = %q, %v", starred.Starred, err)
+	}
+	unstarred, err := client.UnstarBookmark(ctx, added.BookmarkID)
+	if err != nil || unstarred.Starred != "0" {
+		t.Errorf("UnstarBookmark() = %q, %v", unstarred.Starred, err)
+	}
+	if _, err := client.MoveBookmark(ctx, added.BookmarkID, fakeserver.FolderRecipes); err != nil {
+		t.Errorf("MoveBookmark() error = %v", err)
+	}
+	if _, err := client.ArchiveBookmark(ctx, added.BookmarkID); err != nil {
+		t.Errorf("ArchiveBookmark() error = %v", err)
+	}
+	if _, folder, _ := srv.Bookmark(added.BookmarkID); folder != instapaper.FolderArchive {
+		t.Errorf("archived bookmark is in folder %q", folder)
+	}
+	if _, err := client.UnarchiveBookmark(ctx, added.BookmarkID); err != nil {
+		t.Errorf("UnarchiveBookmark() error = %v", err)
+	}
+
+	now := time.Now()
+	progressed, err := client.UpdateReadProgress(ctx, added.BookmarkID, 0.75, now)
+	if err != nil {
+		t.Fatalf("UpdateReadProgress() error = %v", err)
+	}
+	if progressed.Progress != 0.75 || progressed.ProgressTimestamp != now.Unix() {
+		t.Errorf("UpdateReadProgress() = %v at %d, want 0.75 at %d", progressed.Progress, progressed.ProgressTimestamp, now.Unix())
+	}
+	if _, err := client.UpdateReadProgress(ctx, added.BookmarkID, 1.5, now); err == nil {
+		t.Error("UpdateReadProgress() with progress 1.5 succeeded")
+	}
+
+	if err := client.DeleteBookmark(ctx, added.BookmarkID); err != nil {
+		t.Fatalf("DeleteBookmark() error = %v", err)
+	}
+	if _, _, ok := srv.Bookmark(added.BookmarkID); ok {
+		t.Error("deleted bookmark still exists")
+	}
+	if err := client.DeleteBookmark(ctx, added.BookmarkID); !errors.As(err, &apiErr) || apiErr.Code != 1241 {
+		t.Errorf("DeleteBookmark() of a deleted bookmark error = %v, want error 1241", err)
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name       string
+		failure    *fakeserver.Failure
+		call       func(instapaper.Client) error
+		want       error
+		wantCode   int
+		wantStatus int
+	}{
+		{
+			name: "invalid URL",
+			call: func(c instapaper.Client) error {
+				_, err := c.AddBookmark(ctx, instapaper.AddBookmarkParams{URL: "not a url"})
+				return err
+			},
+			want: instapaper.ErrInvalidURL, wantCode: 1240, wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "invalid bookmark",
+			call: func(c instapaper.Client) error {
+				_, err := c.StarBookmark(ctx, 999)
+				return err
+			},
+			want: instapaper.ErrInvalidBookmark, wantCode: 1241, wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "invalid folder",
+			call: func(c instapaper.Client) error {
+				_, err := c.MoveBookmark(ctx, fakeserver.BookmarkSpec, 999)
+				return err
+			},
+			want: instapaper.ErrInvalidFolder, wantCode: 1242, wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:    "invalid progress",
+			failure: &fakeserver.Failure{Status: http.StatusBadRequest, Code: 1243},
+			call: func(c instapaper.Client) error {
+				_, err := c.UpdateReadProgress(ctx, fakeserver.BookmarkSpec, 0.5, time.Now())
+				return err
+			},
+			want: instapaper.ErrInvalidProgress, wantCode: 1243, wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "text unavailable",
+			call: func(c instapaper.Client) error {
+				_, err := c.GetBookmarkText(ctx, fakeserver.BookmarkNoText)
+				return err
+			},
+			want: instapaper.ErrTextUnavailable, wantCode: 1550, wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:    "premium required",
+			failure: &fakeserver.Failure{Status: http.StatusBadRequest, Code: 1041},
+			call: func(c instapaper.Client) error {
+				_, err := c.ListHighlights(ctx, fakeserver.BookmarkSpec)
+				return err
+			},
+			want: instapaper.ErrPremiumRequired, wantCode: 1041, wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:    "service unavailable without a body",
+			failure: &fakeserver.Failure{Status: http.StatusServiceUnavailable},
+			call: func(c instapaper.Client) error {
+				_, err := c.ListFolders(ctx)
+				return err
+			},
+			want: instapaper.ErrServiceUnavailable, wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:    "unauthorized",
+			failure: &fakeserver.Failure{Status: http.StatusUnauthorized},
+			call: func(c instapaper.Client) error {
+				_, err := c.ListFolders(ctx)
+				return err
+			},
+			want: instapaper.ErrUnauthorized, wantStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, client := newTestClient(t, instapaper.WithRetries(-1, 0))
+			if tt.failure != nil {
+				srv.FailNext(1, *tt.failure)
+			}
+			err := tt.call(client)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("error = %v, want %v", err, tt.want)
+			}
+			var apiErr *instapaper.APIError
+			if !errors.As(err, &apiErr) || apiErr.Code != tt.wantCode || apiErr.StatusCode != tt.wantStatus {
+				t.Errorf("error = %#v, want code %d and status %d", err, tt.wantCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestFolders(t *testing.T) {
+	ctx := context.Background()
+	srv, client := newTestClient(t)
+
+	folder, err := client.AddFolder(ctx, "Later")
+	if err != nil {
+		t.Fatalf("AddFolder() error = %v", err)
+	}
+	var apiErr *instapaper.APIError
+	if _, err := client.AddFolder(ctx, "later"); !errors.As(err, &apiErr) || apiErr.Code != 1251 {
+		t.Errorf("AddFolder() with a duplicate title error = %v, want error 1251", err)
+	}
+
+	folders, err := client.SetFolderOrder(ctx, []int64{folder.FolderID, fakeserver.FolderTech, fakeserver.FolderRecipes})
+	if err != nil {
+		t.Fatalf("SetFolderOrder() error = %v", err)
+	}
+	titles := []string{}
+	for _, f := range folders {
+		titles = append(titles, f.Title)
+	}
+	if want := []string{"Later", "Tech", "Recipes"}; !slices.Equal(titles, want) {
+		t.Errorf("SetFolderOrder() = %v, want %v", titles, want)
+	}
+
+	if err := client.DeleteFolder(ctx, fakeserver.FolderRecipes); err != nil {
+		t.Fatalf("DeleteFolder() error = %v", err)
+	}
+	folders, err = client.ListFolders(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(folders) != 2 {
+		t.Errorf("ListFolders() after delete returned %d folders, want 2", len(folders))
+	}
+	if _, f, _ := srv.Bookmark(fakeserver.BookmarkSourdough); f != instapaper.FolderArchive {
+		t.Errorf("bookmark of the deleted folder is in %q, want the archive", f)
+	}
+}
+
+func TestHighlights(t *testing.T) {
+	ctx := context.Background()
+	_, client := newTestClient(t)
+
+	highlights, err := client.ListHighlights(ctx, fakeserver.BookmarkSpec)
+	if err != nil {
+		t.Fatalf("ListHighlights() error = %v", err)
+	}
+	if len(highlights) != 1 || highlights[0].HighlightID != fakeserver.HighlightSpec {
+		t.Errorf("ListHighlights() = %+v, want the seeded highlight", highlights)
+	}
+
+	created, err := client.CreateHighlight(ctx, fakeserver.BookmarkSpec, "strongly typed", 0)
+	if err != nil {
+		t.Fatalf("CreateHighlight() error = %v", err)
+	}
+	if created.Text != "strongly typed" || created.BookmarkID != fakeserver.BookmarkSpec {
+		t.Errorf("CreateHighlight() = %+v", created)
+	}
+	if _, err := client.CreateHighlight(ctx, fakeserver.BookmarkSpec, "strongly typed", 0); err == nil {
+		t.Error("CreateHighlight() of a duplicate succeeded")
+	}
+
+	if err := client.DeleteHighlight(ctx, fakeserver.HighlightSpec); err != nil {
+		t.Fatalf("DeleteHighlight() error = %v", err)
+	}
+	highlights, err = client.ListHighlights(ctx, fakeserver.BookmarkSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(highlights) != 1 || highlights[0].HighlightID != created.HighlightID {
+		t.Errorf("ListHighlights() after delete = %+v, want only the created highlight", highlights)
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	ctx := context.Background()
+	srv, client := newTestClient(t)
+	srv.RevokeTokens()
+
+	if _, err := client.ListBookmarks(ctx, instapaper.ListOptions{Limit: 10}); !errors.Is(err, instapaper.ErrUnauthorized) {
+		t.Errorf("ListBookmarks() error = %v, want %v", err, instapaper.ErrUnauthorized)
+	}
+	if _, err := client.GetBookmarkText(ctx, fakeserver.BookmarkSpec); !errors.Is(err, instapaper.ErrUnauthorized) {
+		t.Errorf("GetBookmarkText() error = %v, want %v", err, instapaper.ErrUnauthorized)
+	}
+	if _, err := client.ListFolders(ctx); !errors.Is(err, instapaper.ErrUnauthorized) {
+		t.Errorf("ListFolders() error = %v, want %v", err, instapaper.ErrUnauthorized)
+	}
+}
+
+func TestBadSignature(t *testing.T) {
+	ctx := context.Background()
+	srv := fakeserver.New()
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	client, err := instapaper.NewClient(
+		instapaper.WithBaseURL(ts.URL+fakeserver.BasePath),
+		instapaper.WithCredentials(fakeserver.ConsumerKey, "wrong secret"),
+		instapaper.WithToken(srv.IssueToken()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ListFolders(ctx); !errors.Is(err, instapaper.ErrUnauthorized) {
+		t.Errorf("ListFolders() signed with the wrong secret error = %v, want %v", err, instapaper.ErrUnauthorized)
+	}
+}
+
+func TestContextCanceled(t *testing.T) {
+	// a server that doesn't answer until the test is done
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer ts.Close()
+	defer close(done)
+	client, err := instapaper.NewClient(fakeserver.ClientOptions(ts.URL)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.ListBookmarks(ctx, instapaper.ListOptions{Limit: 10}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ListBookmarks() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.GetBookmarkText(ctx, fakeserver.BookmarkSpec); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetBookmarkText() error = %v, want %v", err, context.Canceled)
+	}
+	if _, err := instapaper.Login(ctx, fakeserver.Username, fakeserver.Password, fakeserver.ClientOptions(ts.URL)...); !errors.Is(err, context.Canceled) {
+		t.Errorf("Login() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	rateLimited := fakeserver.Failure{Status: http.StatusBadRequest, Code: 1040}
+	unavailable := fakeserver.Failure{Status: http.StatusServiceUnavailable}
+	tests := []struct {
+		name         string
+		failures     int
+		failure      fakeserver.Failure
+		call         func(instapaper.Client) error
+		wantErr      bool
+		wantRequests int
+	}{
+		{
+			name:     "rate limited list",
+			failures: 2, failure: rateLimited,
+			call: func(c instapaper.Client) error {
+				_, err := c.ListBookmarks(ctx, instapaper.ListOptions{Limit: 10})
+				return err
+			},
+			wantRequests: 3,
+		},
+		{
+			name:     "unavailable list",
+			failures: 2, failure: unavailable,
+			call: func(c instapaper.Client) error {
+				_, err := c.ListFolders(ctx)
+				return err
+			},
+			wantRequests: 3,
+		},
+		{
+			name:     "rate limited add",
+			failures: 1, failure: rateLimited,
+			call: func(c instapaper.Client) error {
+				_, err := c.AddBookmark(ctx, instapaper.AddBookmarkParams{URL: "https://example.com/retry"})
+				return err
+			},
+			wantRequests: 2,
+		},
+		{
+			// the bookmark may have been saved, sending it again could add it twice
+			name:     "unavailable add",
+			failures: 1, failure: unavailable,
+			call: func(c instapaper.Client) error {
+				_, err := c.AddBookmark(ctx, instapaper.AddBookmarkParams{URL: "https://example.com/retry"})
+				return err
+			},
+			wantErr:      true,
+			wantRequests: 1,
+		},
+		{
+			name:     "too many failures",
+			failures: 4, failure: unavailable,
+			call: func(c instapaper.Client) error {
+				_, err := c.ListFolders(ctx)
+				return err
+			},
+			wantErr:      true,
+			wantRequests: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, client := newTestClient(t, instapaper.WithRetries(3, time.Millisecond))
+			srv.FailNext(tt.failures, tt.failure)
+			err := tt.call(client)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := srv.Requests(); got != tt.wantRequests {
+				t.Errorf("server got %d requests, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestRetryRateLimit(t *testing.T) {
+	ctx := context.Background()
+	srv, client := newTestClient(t, instapaper.WithRetries(2, time.Millisecond))
+
+	srv.FailNext(3, fakeserver.Failure{Status: http.StatusTooManyRequests})
+	_, err := client.ListFolders(ctx)
+	var rateLimitErr *instapaper.RateLimitError
+	if !errors.Is(err, instapaper.ErrRateLimited) || !errors.As(err, &rateLimitErr) {
+		t.Fatalf("ListFolders() error = %v, want a RateLimitError", err)
+	}
+
+	// waiting this long is not worth it
+	srv.FailNext(1, fakeserver.Failure{Status: http.StatusTooManyRequests, RetryAfter: "3600"})
+	before := srv.Requests()
+	_, err = client.ListFolders(ctx)
+	if !errors.As(err, &rateLimitErr) || rateLimitErr.RetryAfter != time.Hour {
+		t.Fatalf("ListFolders() error = %v, want a RateLimitError to retry after an hour", err)
+	}
+	if got := srv.Requests() - before; got != 1 {
+		t.Errorf("server got %d requests, want 1", got)
+	}
+
+	srv.FailNext(1, fakeserver.Failure{Status: http.StatusTooManyRequests, RetryAfter: "1"})
+	start := time.Now()
+	if _, err := client.ListFolders(ctx); err != nil {
+		t.Fatalf("ListFolders() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, want Retry-After to be honored", elapsed)
+	}
+}
+
+func TestPrivateBookmark(t *testing.T) {
+	ctx := context.Background()
+	_, client := newTestClient(t)
+
+	params := instapaper.AddBookmarkParams{Title: "Notes", Content: "<p>private</p>", PrivateSource: "gopaper"}
+	added, err := client.AddBookmark(ctx, params)
+	if err != nil {
+		t.Fatalf("AddBookmark() error = %v", err)
+	}
+	if added.PrivateSource != "gopaper" || added.Title != "Notes" {
+		t.Errorf("AddBookmark() = %+v, want a private bookmark titled Notes", added)
+	}
+	text, err := client.GetBookmarkText(ctx, added.BookmarkID)
+	if err != nil || !strings.Contains(text, "private") {
+		t.Errorf("GetBookmarkText() = %q, %v, want the uploaded content", text, err)
+	}
+	// private bookmarks have no URL to update, every upload is a new bookmark
+	again, err := client.AddBookmark(ctx, params)
+	if err != nil || again.BookmarkID == added.BookmarkID {
+		t.Errorf("AddBookmark() again = %d, %v, want a new bookmark", again.BookmarkID, err)
+	}
+	var apiErr *instapaper.APIError
+	params.Content = ""
+	if _, err := client.AddBookmark(ctx, params); !errors.As(err, &apiErr) || apiErr.Code != 1245 {
+		t.Errorf("AddBookmark() of a private bookmark without content error = %v, want error 1245", err)
+	}
+}
+
+func TestBookmarkTags(t *testing.T) {
+	ctx := context.Background()
+	srv, client := newTestClient(t)
+
+	added, err := client.AddBookmark(ctx, instapaper.AddBookmarkParams{URL: "https://example.com/tagged", Tags: []string{"a", "b"}})
+	if err != nil {
+		t.Fatalf("AddBookmark() error = %v", err)
+	}
+	if got := instapaper.TagNames(added.Tags); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("AddBookmark() tags = %v, want [a b]", got)
+	}
+	updated, err := client.SetBookmarkTags(ctx, added.URL, instapaper.FolderUnread, nil)
+	if err != nil || len(updated.Tags) != 0 {
+		t.Errorf("SetBookmarkTags() without tags = %v, %v, want no tags", updated.Tags, err)
+	}
+	// setting tags does not move the bookmark back to Home
+	spec, _, _ := srv.Bookmark(fakeserver.BookmarkSpec)
+	if _, err := client.ArchiveBookmark(ctx, spec.BookmarkID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.SetBookmarkTags(ctx, spec.URL, instapaper.FolderArchive, []string{"go", "spec"}); err != nil {
+		t.Fatalf("SetBookmarkTags() error = %v", err)
+	}
+	if _, folder, _ := srv.Bookmark(spec.BookmarkID); folder != instapaper.FolderArchive {
+		t.Errorf("bookmark is in folder %q after setting tags, want archive", folder)
+	}
+
+	// private bookmarks have no URL to save the tags with
+	private, err := client.AddBookmark(ctx, instapaper.AddBookmarkParams{Title: "Notes", Content: "<p>private</p>", PrivateSource: "gopaper", Tags: []string{"go"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.SetBookmarkTags(ctx, private.URL, instapaper.FolderUnread, []string{"notes"}); !errors.Is(err, instapaper.ErrPrivateBookmark) {
+		t.Errorf("SetBookmarkTags() of a private bookmark error = %v, want ErrPrivateBookmark", err)
+	}
+
+	renamed, skipped, err := client.RenameTag(ctx, "go", "golang")
+	if err != nil {
+		t.Fatalf("RenameTag() error = %v", err)
+	}
+	want := []int64{fakeserver.BookmarkBubbleTea, fakeserver.BookmarkSpec, fakeserver.BookmarkNoText}
+	if got := bookmarkIDs(renamed); !slices.Equal(got, want) || skipped != 1 {
+		t.Errorf("RenameTag() renamed %v and skipped %d, want %v and the private bookmark", got, skipped, want)
+	}
+	if got := tagNames(t, srv, fakeserver.BookmarkSpec); !slices.Equal(got, []string{"golang", "spec"}) {
+		t.Errorf("tags after renaming = %v, want [golang spec]", got)
+	}
+	// the bookmarks stay in the archive and in their folder
+	for id, want := range map[int64]string{fakeserver.BookmarkSpec: instapaper.FolderArchive, fakeserver.BookmarkNoText: strconv.FormatInt(fakeserver.FolderTech, 10)} {
+		if _, folder, _ := srv.Bookmark(id); folder != want {
+			t.Errorf("bookmark %d is in folder %q after renaming a tag, want %q", id, folder, want)
+		}
+	}
+	// the bookmark has both tags
+	if _, _, err := client.RenameTag(ctx, "tui", "golang"); err != nil {
+		t.Fatalf("RenameTag() error = %v", err)
+	}
+	if got := tagNames(t, srv, fakeserver.BookmarkBubbleTea); !slices.Equal(got, []string{"golang"}) {
+		t.Errorf("tags after merging = %v, want [golang]", got)
+	}
+}
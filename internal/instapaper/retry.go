@@ -0,0 +1,215 @@
+// Retrying failed requests
+package instapaper
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ieroNo47/gopaper/internal/instapaper/xauth"
+)
+
+const (
+	defaultMaxRetries   = 3
+	defaultRetryBackoff = 500 * time.Millisecond
+	// maxRetryWait caps the backoff and a Retry-After the server asks for.
+	// If the server wants us to wait longer we give up right away.
+	maxRetryWait = 30 * time.Second
+)
+
+// RateLimitError is returned when the retries are exhausted on a rate limited request.
+// It matches ErrRateLimited.
+type RateLimitError struct {
+	// RetryAfter is how long the server asked to wait, 0 if it didn't say
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%v, retry after %v", ErrRateLimited, e.RetryAfter)
+	}
+	return ErrRateLimited.Error()
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// nonIdempotentEndpoints create something on every call, so they are only retried
+// when they were rate limited, which means the server did not process them
+var nonIdempotentEndpoints = []string{
+	bookmarksAdd,
+	foldersAdd,
+	"/highlight", // bookmarks/%d/highlight
+	xauth.AccessTokenPath,
+}
+
+// retryTransport retries requests that failed because of a rate limit, a server error
+// or a network error, waiting with exponential backoff and jitter in between
+type retryTransport struct {
+	base       http.RoundTripper
+	maxRetries int
+	backoff    time.Duration
+}
+
+func newRetryTransport(base http.RoundTripper, cfg Config) http.RoundTripper {
+	if cfg.MaxRetries < 0 {
+		return base
+	}
+	t := &retryTransport{base: base, maxRetries: cfg.MaxRetries, backoff: cfg.RetryBackoff}
+	if t.maxRetries == 0 {
+		t.maxRetries = defaultMaxRetries
+	}
+	if t.backoff <= 0 {
+		t.backoff = defaultRetryBackoff
+	}
+	return t
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	idempotent := isIdempotent(req)
+	for attempt := 0; ; attempt++ {
+		attemptReq, err := rewind(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := base.RoundTrip(attemptReq)
+
+		rateLimited, retryable := false, false
+		switch {
+		case err != nil:
+			// the request may or may not have reached the server
+			retryable = idempotent && req.Context().Err() == nil
+		case isRateLimited(resp):
+			rateLimited, retryable = true, true
+		case resp.StatusCode >= 500 || hasErrorCode(resp, codeServiceError):
+			retryable = idempotent
+		}
+		if !retryable {
+			return resp, err
+		}
+
+		retryAfter := time.Duration(0)
+		if resp != nil {
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		}
+		wait := max(t.backoffFor(attempt), retryAfter)
+		if attempt >= t.maxRetries || wait > maxRetryWait || !fitsDeadline(req.Context(), wait) {
+			if rateLimited {
+				resp.Body.Close()
+				return nil, &RateLimitError{RetryAfter: retryAfter}
+			}
+			return resp, err
+		}
+		if resp != nil {
+			// drain the body so the connection can be reused
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// backoffFor returns the wait before the retry after the given attempt:
+// the backoff doubles with every attempt, with up to half of it randomized
+func (t *retryTransport) backoffFor(attempt int) time.Duration {
+	d := t.backoff
+	for i := 0; i < attempt && d < maxRetryWait; i++ {
+		d *= 2
+	}
+	d = min(d, maxRetryWait)
+	return d/2 + rand.N(d/2+1)
+}
+
+// misc helper functions
+
+// isIdempotent reports whether sending a request twice has the same effect as sending it once
+func isIdempotent(req *http.Request) bool {
+	for _, endpoint := range nonIdempotentEndpoints {
+		if strings.HasSuffix(req.URL.Path, endpoint) {
+			return false
+		}
+	}
+	return true
+}
+
+// rewind returns the request to send for an attempt, with a fresh copy of the body after the first
+func rewind(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 0 || req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	if req.GetBody == nil {
+		return nil, fmt.Errorf("cannot retry %s: the request body can't be read again", req.URL.Path)
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Body = body
+	return req, nil
+}
+
+// isRateLimited reports whether a response is a 429 or has the rate limit error code
+func isRateLimited(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusTooManyRequests || hasErrorCode(resp, codeRateLimited)
+}
+
+// hasErrorCode reports whether an error response has an error object with the code.
+// The body is read and replaced so the caller can still read it.
+func hasErrorCode(resp *http.Response, code int) bool {
+	if resp.StatusCode < 400 {
+		return false
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	apiErr := parseAPIError(body)
+	return apiErr != nil && apiErr.Code == code
+}
+
+// parseRetryAfter parses a Retry-After header, which is either seconds or an HTTP date
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		return max(date.Sub(now), 0)
+	}
+	return 0
+}
+
+// fitsDeadline reports whether there is time left to wait before retrying
+func fitsDeadline(ctx context.Context, wait time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return !ok || time.Until(deadline) > wait
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,160 @@
+// Incremental bookmark sync
+package instapaper
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// Library is a local copy of the bookmarks in a folder. Sync only downloads
+// the bookmarks that changed since the last call by passing the known
+// bookmarks as the have parameter of bookmarks/list.
+// As the known bookmarks are excluded, every sync also loads the next page of limit bookmarks.
+type Library struct {
+	client    Client
+	folderID  string
+	limit     int
+	mu        sync.Mutex
+	bookmarks map[int64]Bookmark
+	// complete is set once a sync returned less than a page, i.e. all bookmarks are loaded
+	complete bool
+}
+
+// SyncResult describes the changes applied by a sync
+type SyncResult struct {
+	Added   []Bookmark
+	Updated []Bookmark
+	Deleted []int64
+}
+
+// Changed reports whether the sync changed the library
+func (r SyncResult) Changed() bool {
+	return len(r.Added) > 0 || len(r.Updated) > 0 || len(r.Deleted) > 0
+}
+
+// NewLibrary creates a library for a folder, seeded with previously synced bookmarks
+func NewLibrary(client Client, folderID string, limit int, bookmarks []Bookmark) *Library {
+	l := &Library{
+		client:    client,
+		folderID:  folderID,
+		limit:     limit,
+		bookmarks: map[int64]Bookmark{},
+	}
+	for _, b := range bookmarks {
+		l.bookmarks[b.BookmarkID] = b
+	}
+	return l
+}
+
+func (l *Library) FolderID() string {
+	return l.folderID
+}
+
+// Sync fetches the changes since the last sync and the next page of bookmarks, and applies them
+func (l *Library) Sync(ctx context.Context) (SyncResult, error) {
+	l.mu.Lock()
+	have := make([]Have, 0, len(l.bookmarks))
+	for _, b := range l.bookmarks {
+		have = append(have, b.Have())
+	}
+	l.mu.Unlock()
+
+	response, err := l.client.ListBookmarks(ctx, ListOptions{
+		Limit:    l.limit,
+		FolderID: l.folderID,
+		Have:     have,
+	})
+	if err != nil {
+		return SyncResult{}, err
+	}
+	result := l.Apply(response)
+	l.mu.Lock()
+	l.complete = len(response.Bookmarks) < l.limit
+	l.mu.Unlock()
+	return result, nil
+}
+
+// Complete reports whether the last sync loaded all bookmarks of the folder
+func (l *Library) Complete() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.complete
+}
+
+// Apply merges a bookmarks/list response into the library
+func (l *Library) Apply(response Response) SyncResult {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	result := SyncResult{}
+	for _, b := range response.Bookmarks {
+		if _, ok := l.bookmarks[b.BookmarkID]; ok {
+			result.Updated = append(result.Updated, b)
+		} else {
+			result.Added = append(result.Added, b)
+		}
+		l.bookmarks[b.BookmarkID] = b
+	}
+	for _, id := range response.DeleteIDs {
+		if _, ok := l.bookmarks[id]; ok {
+			delete(l.bookmarks, id)
+			result.Deleted = append(result.Deleted, id)
+		}
+	}
+	return result
+}
+
+// Update replaces a bookmark that changed locally, e.g. after updating its read progress.
+// It reports whether the bookmark is in the library.
+func (l *Library) Update(bookmark Bookmark) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.bookmarks[bookmark.BookmarkID]; !ok {
+		return false
+	}
+	l.bookmarks[bookmark.BookmarkID] = bookmark
+	return true
+}
+
+// Add puts a bookmark that was saved by the client into the library, so it is listed before the next sync
+func (l *Library) Add(bookmark Bookmark) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.bookmarks[bookmark.BookmarkID] = bookmark
+}
+
+// Remove takes a bookmark out of the library, e.g. after archiving it.
+// It reports whether the bookmark was in the library.
+func (l *Library) Remove(bookmarkID int64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.bookmarks[bookmarkID]; !ok {
+		return false
+	}
+	delete(l.bookmarks, bookmarkID)
+	return true
+}
+
+// Len returns the number of bookmarks in the library
+func (l *Library) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.bookmarks)
+}
+
+// Bookmarks returns the bookmarks in the library, most recently added first
+func (l *Library) Bookmarks() []Bookmark {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	bookmarks := make([]Bookmark, 0, len(l.bookmarks))
+	for _, b := range l.bookmarks {
+		bookmarks = append(bookmarks, b)
+	}
+	sort.Slice(bookmarks, func(i, j int) bool {
+		if bookmarks[i].Time != bookmarks[j].Time {
+			return bookmarks[i].Time > bookmarks[j].Time
+		}
+		return bookmarks[i].BookmarkID > bookmarks[j].BookmarkID
+	})
+	return bookmarks
+}
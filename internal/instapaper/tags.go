@@ -0,0 +1,115 @@
+// tag editing
+package instapaper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+)
+
+// SetBookmarkTags replaces the tags of the bookmark saved with the URL, no tags removes them all.
+// The API has no tag endpoints, the tags are sent with bookmarks/add, which also moves the bookmark
+// to Home. folderID is the folder the bookmark is in, bookmarks in a user-created folder are saved
+// there and archived bookmarks are archived again. Private bookmarks have no URL and cannot be
+// tagged, see ErrPrivateBookmark.
+func (c Client) SetBookmarkTags(ctx context.Context, bookmarkURL, folderID string, tags []string) (Bookmark, error) {
+	if bookmarkURL == "" {
+		return Bookmark{}, ErrPrivateBookmark
+	}
+	values := url.Values{}
+	values.Add("url", bookmarkURL)
+	values.Add("tags", tagsValue(tags))
+	if _, err := strconv.ParseInt(folderID, 10, 64); err == nil {
+		values.Add("folder_id", folderID)
+	}
+	bookmark, err := c.postBookmark(ctx, bookmarksAdd, values)
+	if err != nil || folderID != FolderArchive {
+		return bookmark, err
+	}
+	return c.ArchiveBookmark(ctx, bookmark.BookmarkID)
+}
+
+// RenameTag renames a tag on the bookmarks in Home, the archive and the user-created folders.
+// If a bookmark has both tags they are merged. Private bookmarks keep the tag and are counted
+// in skipped. Returns the updated bookmarks and the errors of the bookmarks and folders that
+// failed, renaming again picks up the remaining bookmarks.
+func (c Client) RenameTag(ctx context.Context, from, to string) (renamed []Bookmark, skipped int, err error) {
+	if from == to {
+		return nil, 0, nil
+	}
+	folderIDs := []string{FolderUnread, FolderArchive}
+	folders, err := c.ListFolders(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, f := range folders {
+		folderIDs = append(folderIDs, f.ID())
+	}
+	renamed = []Bookmark{}
+	errs := []error{}
+	for _, folderID := range folderIDs {
+		response, err := c.ListAllBookmarks(ctx, ListOptions{FolderID: folderID})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list folder %s: %w", folderID, err))
+			continue
+		}
+		for _, bookmark := range response.Bookmarks {
+			tags, ok := RenameTagIn(bookmark.Tags, from, to)
+			switch {
+			case !ok:
+				continue
+			case bookmark.URL == "":
+				skipped++
+				continue
+			}
+			updated, err := c.SetBookmarkTags(ctx, bookmark.URL, folderID, tags)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to rename tag of bookmark %d: %w", bookmark.BookmarkID, err))
+				continue
+			}
+			renamed = append(renamed, updated)
+		}
+	}
+	return renamed, skipped, errors.Join(errs...)
+}
+
+// RenameTagIn returns the tag names with from replaced by to, and whether from was in the tags
+func RenameTagIn(tags []Tag, from, to string) ([]string, bool) {
+	names := TagNames(tags)
+	i := slices.Index(names, from)
+	if i < 0 {
+		return names, false
+	}
+	if slices.Contains(names, to) {
+		return slices.Delete(names, i, i+1), true
+	}
+	names[i] = to
+	return names, true
+}
+
+// TagNames returns the names of the tags
+func TagNames(tags []Tag) []string {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+// misc helper functions
+
+// tagsValue encodes tag names as the tags parameter of bookmarks/add
+func tagsValue(names []string) string {
+	tags := make([]struct {
+		Name string `json:"name"`
+	}, len(names))
+	for i, name := range names {
+		tags[i].Name = name
+	}
+	value, _ := json.Marshal(tags)
+	return string(value)
+}
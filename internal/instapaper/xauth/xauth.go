@@ -2,62 +2,114 @@
 package xauth
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha1"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"os"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 )
 
-const oauthAccessTokenPath = "oauth/access_token"
+// AccessTokenPath is the endpoint path relative to the versioned API root
+const AccessTokenPath = "oauth/access_token"
 
-func GetToken() (url.Values, error) {
-	signingKey := os.Getenv("IP_OAUTH_CONSUMER_SECRET") + "&"
+// Config has what is needed to request an access token
+type Config struct {
+	// AccessTokenURL is the full URL of the access token endpoint
+	AccessTokenURL string
+	ConsumerKey    string
+	ConsumerSecret string
+	// HTTPClient defaults to http.DefaultClient
+	HTTPClient *http.Client
+}
+
+// Instapaper error codes relevant for logging in
+const (
+	codeRateLimited  = 1040
+	codeServiceError = 1500
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrRateLimited        = errors.New("rate limit exceeded, try again later")
+	ErrServiceUnavailable = errors.New("instapaper is unavailable, try again later")
+)
+
+// Error is a failed access token request. It wraps one of the Err* values
+// if the failure could be classified.
+type Error struct {
+	StatusCode int
+	// Code is the Instapaper error code, 0 if the response had none
+	Code    int
+	Message string
+	err     error
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	if e.err != nil {
+		return fmt.Sprintf("%v (status %d: %s)", e.err, e.StatusCode, msg)
+	}
+	return fmt.Sprintf("failed to get access token (status %d: %s)", e.StatusCode, msg)
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// GetToken exchanges a username and password for an OAuth access token.
+// The returned values contain oauth_token and oauth_token_secret.
+func GetToken(ctx context.Context, cfg Config, username, password string) (url.Values, error) {
+	signingKey := cfg.ConsumerSecret + "&"
 	method := "POST"
 	nonce, err := generateNonce(32)
 	if err != nil {
 		return nil, err
 	}
-	accessTokenURL := fmt.Sprintf("%s/%s/%s",
-		os.Getenv("IP_API"),
-		os.Getenv("IP_API_VERSION"),
-		oauthAccessTokenPath)
-	encAccessTokenURL := url.QueryEscape(accessTokenURL)
+	accessTokenURL := cfg.AccessTokenURL
+	encAccessTokenURL := percentEncode(accessTokenURL)
 	parameters := map[string]string{
-		"oauth_consumer_key":     os.Getenv("IP_OAUTH_CONSUMER_ID"),
+		"oauth_consumer_key":     cfg.ConsumerKey,
 		"oauth_nonce":            nonce,
 		"oauth_signature_method": "HMAC-SHA1",
 		"oauth_timestamp":        strconv.Itoa(int(time.Now().Unix())),
 		"oauth_version":          "1.0",
 		"x_auth_mode":            "client_auth",
-		"x_auth_password":        os.Getenv("IP_PASSWORD"),
-		"x_auth_username":        os.Getenv("IP_USER"),
+		"x_auth_password":        password,
+		"x_auth_username":        username,
 	}
-	encParameters := url.QueryEscape(urlEncodeParameters(parameters))
+	encParameters := percentEncode(urlEncodeParameters(parameters))
 	signatureBaseString := fmt.Sprintf("%s&%s&%s", method, encAccessTokenURL, encParameters)
 	parameters["oauth_signature"] = generateSignature(signingKey, signatureBaseString)
 	authorizationHeader := getAuthorizationHeader(parameters)
 	// fmt.Printf("%s\n%s\n%s\n", signatureBaseString, parameters["oauth_signature"], authorizationHeader)
 	values := url.Values{}
 	values.Set("x_auth_mode", "client_auth")
-	values.Set("x_auth_password", os.Getenv("IP_PASSWORD"))
-	values.Set("x_auth_username", os.Getenv("IP_USER"))
-	req, err := http.NewRequest("POST", accessTokenURL, strings.NewReader(values.Encode()))
+	values.Set("x_auth_password", password)
+	values.Set("x_auth_username", username)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, accessTokenURL, strings.NewReader(values.Encode()))
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Set("Authorization", authorizationHeader)
 
-	client := &http.Client{}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
@@ -67,14 +119,59 @@ func GetToken() (url.Values, error) {
 	if err != nil {
 		return nil, err
 	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newError(resp.StatusCode, body)
+	}
 
 	tokenValues, err := url.ParseQuery(string(body))
 	if err != nil {
 		return nil, err
 	}
+	if tokenValues.Get("oauth_token") == "" || tokenValues.Get("oauth_token_secret") == "" {
+		// some errors are returned with a 200 status
+		if e := newError(resp.StatusCode, body); e.Code != 0 {
+			return nil, e
+		}
+		return nil, fmt.Errorf("no token in response: %s", string(body))
+	}
 	return tokenValues, nil
 }
 
+// newError classifies a failed response by the Instapaper error code if the body
+// has one, and by the status code otherwise
+func newError(statusCode int, body []byte) *Error {
+	e := &Error{StatusCode: statusCode, Message: strings.TrimSpace(string(body))}
+	// the body is either plain text or a JSON array of error objects
+	var apiErrors []struct {
+		Type    string `json:"type"`
+		Code    int    `json:"error_code"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &apiErrors); err == nil {
+		for _, apiErr := range apiErrors {
+			if apiErr.Type == "error" {
+				e.Code = apiErr.Code
+				e.Message = apiErr.Message
+				break
+			}
+		}
+	}
+
+	switch {
+	case e.Code == codeRateLimited:
+		e.err = ErrRateLimited
+	case e.Code == codeServiceError:
+		e.err = ErrServiceUnavailable
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		e.err = ErrInvalidCredentials
+	case statusCode == http.StatusTooManyRequests:
+		e.err = ErrRateLimited
+	case statusCode >= 500:
+		e.err = ErrServiceUnavailable
+	}
+	return e
+}
+
 func getAuthorizationHeader(parameters map[string]string) string {
 	headerValue := "OAuth "
 	headerParams := []string{}
@@ -105,8 +202,8 @@ func urlEncodeParameters(parameters map[string]string) string {
 	}
 	sort.Strings(keys)
 	for _, k := range keys {
-		encKey := url.QueryEscape(k)
-		encValue := url.QueryEscape(parameters[k])
+		encKey := percentEncode(k)
+		encValue := percentEncode(parameters[k])
 		encParams = append(encParams, fmt.Sprintf("%s=%s", encKey, encValue))
 	}
 	return strings.Join(encParams, "&")
@@ -120,3 +217,19 @@ func generateNonce(length int) (string, error) {
 	}
 	return base64.StdEncoding.EncodeToString(bytes), nil
 }
+
+// percentEncode encodes a string as required by OAuth (RFC 3986, section 2.1).
+// Unlike url.QueryEscape, spaces become %20 and ~ is left as is.
+func percentEncode(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
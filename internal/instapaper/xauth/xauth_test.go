@@ -0,0 +1,76 @@
+package xauth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ieroNo47/gopaper/internal/instapaper/fakeserver"
+	"github.com/ieroNo47/gopaper/internal/instapaper/xauth"
+)
+
+func TestGetToken(t *testing.T) {
+	ctx := context.Background()
+	ts := httptest.NewServer(fakeserver.New())
+	defer ts.Close()
+	cfg := xauth.Config{
+		AccessTokenURL: ts.URL + fakeserver.BasePath + "/1.1/" + xauth.AccessTokenPath,
+		ConsumerKey:    fakeserver.ConsumerKey,
+		ConsumerSecret: fakeserver.ConsumerSecret,
+	}
+
+	// the password has spaces, which must be encoded as %20 in the signature
+	values, err := xauth.GetToken(ctx, cfg, fakeserver.Username, fakeserver.Password)
+	if err != nil {
+		t.Fatalf("GetToken() error = %v", err)
+	}
+	if values.Get("oauth_token") == "" || values.Get("oauth_token_secret") == "" {
+		t.Errorf("GetToken() = %v, want a token and secret", values)
+	}
+
+	if _, err := xauth.GetToken(ctx, cfg, fakeserver.Username, "wrong"); !errors.Is(err, xauth.ErrInvalidCredentials) {
+		t.Errorf("GetToken() with a wrong password error = %v, want %v", err, xauth.ErrInvalidCredentials)
+	}
+
+	cfg.ConsumerSecret = "wrong"
+	if _, err := xauth.GetToken(ctx, cfg, fakeserver.Username, fakeserver.Password); !errors.Is(err, xauth.ErrInvalidCredentials) {
+		t.Errorf("GetToken() with a wrong consumer secret error = %v, want %v", err, xauth.ErrInvalidCredentials)
+	}
+}
+
+func TestGetTokenErrors(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		want     error
+		wantCode int
+	}{
+		{"rate limit code", http.StatusBadRequest, `[{"type":"error","error_code":1040,"message":"Rate-limit exceeded"}]`, xauth.ErrRateLimited, 1040},
+		{"service error code", http.StatusOK, `[{"type":"error","error_code":1500,"message":"Unexpected service error"}]`, xauth.ErrServiceUnavailable, 1500},
+		{"too many requests", http.StatusTooManyRequests, "slow down", xauth.ErrRateLimited, 0},
+		{"forbidden", http.StatusForbidden, "Invalid xAuth credentials.", xauth.ErrInvalidCredentials, 0},
+		{"bad gateway", http.StatusBadGateway, "", xauth.ErrServiceUnavailable, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer ts.Close()
+
+			_, err := xauth.GetToken(ctx, xauth.Config{AccessTokenURL: ts.URL}, "user", "password")
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("GetToken() error = %v, want %v", err, tt.want)
+			}
+			var xerr *xauth.Error
+			if !errors.As(err, &xerr) || xerr.Code != tt.wantCode || xerr.StatusCode != tt.status {
+				t.Errorf("GetToken() error = %#v, want code %d and status %d", err, tt.wantCode, tt.status)
+			}
+		})
+	}
+}
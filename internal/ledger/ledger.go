@@ -0,0 +1,102 @@
+// Record of the bookmarks added by imports
+package ledger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const appDir = "gopaper"
+
+// Ledger remembers what was imported, so running an import again skips it. It is kept with
+// the config rather than the cache since clearing the cache must not import everything again.
+// Every import appends a line to the file:
+//
+//	{"key":"message:<Message-ID>","bookmark_id":1}
+type Ledger struct {
+	path string
+	// mu serializes the appends
+	mu sync.Mutex
+}
+
+type entry struct {
+	Key        string `json:"key"`
+	BookmarkID int64  `json:"bookmark_id"`
+}
+
+// Open returns the ledger of the account with userID in $XDG_CONFIG_HOME/gopaper,
+// ~/.config/gopaper by default. Every account has its own ledger, so an import into
+// one account is not skipped in another.
+func Open(userID int) (*Ledger, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find config dir: %w", err)
+	}
+	return New(filepath.Join(dir, appDir, fmt.Sprintf("imported-%d.jsonl", userID))), nil
+}
+
+// New returns a ledger that is stored in the file at path
+func New(path string) *Ledger {
+	return &Ledger{path: path}
+}
+
+// Imported returns the bookmark IDs of everything imported so far by import key,
+// e.g. the Message-ID of a newsletter
+func (l *Ledger) Imported() (map[string]int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	imported := map[string]int64{}
+	data, err := os.ReadFile(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return imported, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read imports: %w", err)
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		var e entry
+		// lines cut off by an interrupted write are skipped, the import is done again
+		if err := json.Unmarshal(line, &e); err != nil || e.Key == "" {
+			continue
+		}
+		imported[e.Key] = e.BookmarkID
+	}
+	return imported, nil
+}
+
+// Save records that key was imported as a bookmark, so it is skipped next time
+func (l *Ledger) Save(key string, bookmarkID int64) error {
+	line, err := json.Marshal(entry{Key: key, BookmarkID: bookmarkID})
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return fmt.Errorf("failed to create config dir: %w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to save import: %w", err)
+	}
+	// a line cut off by an interrupted write is ended, so it is not merged with this one
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			line = append([]byte("\n"), line...)
+		}
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to save import: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to save import: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package ledger_test
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ieroNo47/gopaper/internal/ledger"
+)
+
+func TestLedger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gopaper", "imported.jsonl")
+	l := ledger.New(path)
+	if imported, err := l.Imported(); err != nil || len(imported) != 0 {
+		t.Fatalf("Imported() of a new ledger = %v, %v, want nothing", imported, err)
+	}
+	if err := l.Save("a", 1); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// a write that was cut off
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"key":"b","book`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if imported, err := l.Imported(); err != nil || !maps.Equal(imported, map[string]int64{"a": 1}) {
+		t.Errorf("Imported() after a cut off write = %v, %v, want only a", imported, err)
+	}
+
+	// the next save is not merged with the cut off line
+	if err := l.Save("c", 3); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := ledger.New(path).Save("d", 4); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	want := map[string]int64{"a": 1, "c": 3, "d": 4}
+	if imported, err := l.Imported(); err != nil || !maps.Equal(imported, want) {
+		t.Errorf("Imported() = %v, %v, want %v", imported, err, want)
+	}
+}
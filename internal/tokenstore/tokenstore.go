@@ -0,0 +1,96 @@
+// Persistent storage of the Instapaper access token
+package tokenstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ieroNo47/gopaper/internal/instapaper"
+)
+
+const (
+	appDir    = "gopaper"
+	tokenFile = "token.json"
+)
+
+// ErrNotFound is returned by Load when no token has been stored yet
+var ErrNotFound = errors.New("no stored token, run gopaper login")
+
+// Path returns the location of the token file, $XDG_CONFIG_HOME/gopaper/token.json on Linux
+func Path() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find config dir: %w", err)
+	}
+	return filepath.Join(dir, appDir, tokenFile), nil
+}
+
+func Load() (instapaper.Token, error) {
+	path, err := Path()
+	if err != nil {
+		return instapaper.Token{}, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return instapaper.Token{}, ErrNotFound
+	}
+	if err != nil {
+		return instapaper.Token{}, fmt.Errorf("failed to read token: %w", err)
+	}
+	var token instapaper.Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		return instapaper.Token{}, fmt.Errorf("failed to parse token file %s: %w", path, err)
+	}
+	return token, nil
+}
+
+// Save writes the token to a file only readable by the current user
+func Save(token instapaper.Token) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config dir: %w", err)
+	}
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	// write to a temp file first so a failed write doesn't leave a broken token behind
+	tmp, err := os.CreateTemp(filepath.Dir(path), tokenFile+".*")
+	if err != nil {
+		return fmt.Errorf("failed to save token: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save token: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save token: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save token: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save token: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the stored token. It is not an error if there is none.
+func Delete() error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete token: %w", err)
+	}
+	return nil
+}
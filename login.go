@@ -0,0 +1,192 @@
+// login view
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ieroNo47/gopaper/internal/cache"
+	"github.com/ieroNo47/gopaper/internal/instapaper"
+	"github.com/ieroNo47/gopaper/internal/instapaper/xauth"
+	"github.com/ieroNo47/gopaper/internal/tokenstore"
+)
+
+var loginStyle = lipgloss.NewStyle().
+	Margin(0).
+	Padding(0).
+	BorderStyle(lipgloss.RoundedBorder()).
+	BorderForeground(lipgloss.Color("5")).
+	MarginBackground(lipgloss.Color("5"))
+
+var loginTitleStyle = lipgloss.NewStyle().Bold(true).MarginBottom(1)
+
+type loginKeyMap struct {
+	next   key.Binding
+	submit key.Binding
+	quit   key.Binding
+}
+
+var loginKeys = loginKeyMap{
+	next: key.NewBinding(
+		key.WithKeys("tab", "shift+tab", "up", "down"),
+		key.WithHelp("tab", "next field"),
+	),
+	submit: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("enter", "log in"),
+	),
+	quit: key.NewBinding(
+		key.WithKeys("ctrl+c"),
+		key.WithHelp("ctrl+c", "quit"),
+	),
+}
+
+// showLoginMsg is sent when there is no stored token
+type showLoginMsg struct{}
+
+// loginErrMsg is sent when logging in or using the stored token failed
+type loginErrMsg struct {
+	err error
+}
+
+// loginWith logs in, stores the token and creates a client with it. The cache is cleared
+// if it has the data of another account.
+func loginWith(ctx context.Context, c *cache.Cache, username, password string) tea.Cmd {
+	return func() tea.Msg {
+		token, err := instapaper.Login(ctx, username, password, clientOptions()...)
+		if err != nil {
+			return loginErrMsg{err}
+		}
+		if err := tokenstore.Save(token); err != nil {
+			return loginErrMsg{err}
+		}
+		client, err := instapaper.NewClient(clientOptions(instapaper.WithToken(token))...)
+		if err != nil {
+			return loginErrMsg{err}
+		}
+		cleared, err := forgetOtherAccount(ctx, client, c)
+		if err != nil {
+			return loginErrMsg{err}
+		}
+		return initClientMsg{client: client, accountChanged: cleared}
+	}
+}
+
+type loginModel struct {
+	ctx      context.Context
+	cache    *cache.Cache
+	username textinput.Model
+	password textinput.Model
+	loading  bool
+	err      error
+	width    int
+	height   int
+}
+
+func newLoginModel(ctx context.Context, c *cache.Cache) loginModel {
+	m := loginModel{
+		ctx:      ctx,
+		cache:    c,
+		username: textinput.New(),
+		password: textinput.New(),
+	}
+	m.username.Prompt = "Username: "
+	m.username.Placeholder = "my-instapaper@email.com"
+	m.username.SetValue(os.Getenv("IP_USER"))
+	m.password.Prompt = "Password: "
+	m.password.EchoMode = textinput.EchoPassword
+	return m
+}
+
+// focus puts the cursor in the first field that needs input
+func (m loginModel) focus() (loginModel, tea.Cmd) {
+	m.username.Blur()
+	m.password.Blur()
+	if m.username.Value() == "" {
+		return m, m.username.Focus()
+	}
+	return m, m.password.Focus()
+}
+
+func (m *loginModel) setSize(width, height int) {
+	m.width = width
+	m.height = height
+	m.username.Width = width / 2
+	m.password.Width = width / 2
+}
+
+func (m loginModel) Update(msg tea.Msg) (loginModel, tea.Cmd) {
+	var cmd tea.Cmd
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if m.loading {
+			return m, nil
+		}
+		switch {
+		case key.Matches(msg, loginKeys.next):
+			if m.username.Focused() {
+				m.username.Blur()
+				return m, m.password.Focus()
+			}
+			m.password.Blur()
+			return m, m.username.Focus()
+		case key.Matches(msg, loginKeys.submit):
+			if m.username.Focused() {
+				m.username.Blur()
+				return m, m.password.Focus()
+			}
+			if m.username.Value() == "" {
+				return m, m.username.Focus()
+			}
+			m.loading = true
+			m.err = nil
+			return m, loginWith(m.ctx, m.cache, m.username.Value(), m.password.Value())
+		}
+		if m.username.Focused() {
+			m.username, cmd = m.username.Update(msg)
+		} else {
+			m.password, cmd = m.password.Update(msg)
+		}
+		return m, cmd
+	case loginErrMsg:
+		m.loading = false
+		m.err = msg.err
+		if errors.Is(msg.err, xauth.ErrInvalidCredentials) {
+			m.password.Reset()
+		}
+		return m.focus()
+	}
+	return m, nil
+}
+
+func (m loginModel) View() string {
+	status := ""
+	switch {
+	case m.loading:
+		status = "Logging in..."
+	case m.err != nil:
+		status = errStyle.Width(m.width / 2).Render(m.err.Error())
+	}
+	form := lipgloss.JoinVertical(
+		lipgloss.Left,
+		loginTitleStyle.Render("Log in to Instapaper"),
+		m.username.View(),
+		m.password.View(),
+		"",
+		status,
+	)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, form)
+}
+
+func (m loginModel) ShortHelp() []key.Binding {
+	return []key.Binding{loginKeys.next, loginKeys.submit, loginKeys.quit}
+}
+
+func (m loginModel) FullHelp() [][]key.Binding {
+	return [][]key.Binding{m.ShortHelp()}
+}
@@ -2,18 +2,26 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io/fs"
 	"log"
-	"sort"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/table"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/ieroNo47/gopaper/internal/cache"
 	"github.com/ieroNo47/gopaper/internal/instapaper"
+	"github.com/ieroNo47/gopaper/internal/tokenstore"
 	"github.com/joho/godotenv"
 )
 
@@ -22,6 +30,19 @@ type sessionState uint
 const (
 	bookmarksView sessionState = iota
 	tagsView
+	foldersView
+	highlightsView
+	loginView
+	readerView
+	addBookmarkView
+	tagEditorView
+)
+
+// bookmarkLimit is the number of bookmarks loaded at a time, the next page is
+// loaded when the cursor gets within loadMoreThreshold items of the end of the list
+const (
+	bookmarkLimit     = 50
+	loadMoreThreshold = 10
 )
 
 var outerStyle = lipgloss.NewStyle().
@@ -46,6 +67,15 @@ var tagsStyle = lipgloss.NewStyle().
 	BorderForeground(lipgloss.Color("0")).
 	MarginBackground(lipgloss.Color("0"))
 
+var foldersStyle = lipgloss.NewStyle().
+	Margin(0).
+	Padding(0).
+	BorderStyle(lipgloss.RoundedBorder()).
+	BorderForeground(lipgloss.Color("0")).
+	MarginBackground(lipgloss.Color("0"))
+
+var errStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+
 var helpStyle = lipgloss.NewStyle().
 	Margin(0).
 	Padding(0).
@@ -54,68 +84,327 @@ var helpStyle = lipgloss.NewStyle().
 	MarginBackground(lipgloss.Color("4"))
 
 type item struct {
-	title string
-	desc  string
-	tags  []instapaper.Tag
+	bookmark instapaper.Bookmark
+	title    string
+	desc     string
+	tags     []instapaper.Tag
 }
 
+func (i item) ID() int64              { return i.bookmark.BookmarkID }
 func (i item) Title() string          { return i.title }
 func (i item) Description() string    { return i.desc }
 func (i item) FilterValue() string    { return i.title }
 func (i item) Tags() []instapaper.Tag { return i.tags }
+func (i item) Starred() bool          { return i.bookmark.Starred == "1" }
 
-type initListMsg []list.Item
+type keyMap struct {
+	read       key.Binding
+	openFolder key.Binding
+	highlights key.Binding
+	refresh    key.Binding
+	add        key.Binding
+}
 
-func initList() tea.Cmd {
+var keys = keyMap{
+	read: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("enter", "read"),
+	),
+	openFolder: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("enter", "open folder"),
+	),
+	highlights: key.NewBinding(
+		key.WithKeys("H"),
+		key.WithHelp("H", "highlights"),
+	),
+	refresh: key.NewBinding(
+		key.WithKeys("r"),
+		key.WithHelp("r", "refresh"),
+	),
+	add: key.NewBinding(
+		key.WithKeys("a"),
+		key.WithHelp("a", "add"),
+	),
+}
+
+// folder is an entry of the folders table
+type folder struct {
+	id    string
+	title string
+}
+
+// the special folders are always listed first
+var defaultFolders = []folder{
+	{id: instapaper.FolderUnread, title: "Home"},
+	{id: instapaper.FolderStarred, title: "Starred"},
+	{id: instapaper.FolderArchive, title: "Archive"},
+}
+
+type initClientMsg struct {
+	client instapaper.Client
+	// accountChanged is set when the cache of the previous account was cleared
+	accountChanged bool
+}
+
+func initClient(ctx context.Context) tea.Cmd {
 	return func() tea.Msg {
-		client, err := instapaper.NewClient()
+		token, err := loadToken(ctx)
+		if errors.Is(err, tokenstore.ErrNotFound) {
+			return showLoginMsg{}
+		}
 		if err != nil {
-			log.Fatalf("Failed to init Instapaper client: %v\n", err)
+			return loginErrMsg{err}
 		}
-		bookmarks, err := client.GetBookmarks(50)
+		client, err := instapaper.NewClient(clientOptions(instapaper.WithToken(token))...)
 		if err != nil {
-			log.Fatalf("Failed to get bookmarks: %v\n", err)
+			return loginErrMsg{err}
 		}
+		return initClientMsg{client: client}
+	}
+}
+
+type initListMsg struct {
+	folderID string
+	items    []list.Item
+}
+
+// syncErrMsg is sent when bookmarks or folders could not be synced, e.g. when offline.
+// kind is the notification shown for it.
+type syncErrMsg struct {
+	kind  string
+	err   error
+	retry retryFunc
+}
+
+// cacheMsg has the bookmarks and folders stored by previous runs
+type cacheMsg struct {
+	folderID string
+	items    []list.Item
+	folders  []folder
+	user     instapaper.User
+}
+
+func loadCache(c *cache.Cache, folderID string) tea.Cmd {
+	return func() tea.Msg {
+		folders := append([]folder{}, defaultFolders...)
+		userFolders, _ := c.Folders()
+		for _, f := range userFolders {
+			folders = append(folders, folder{id: f.ID(), title: f.Title})
+		}
+		bookmarks, _ := c.Bookmarks(folderID)
 		items := []list.Item{}
 		for _, bookmark := range bookmarks {
-			tagNames := []string{}
-			for _, tag := range bookmark.Tags {
-				tagNames = append(tagNames, tag.Name)
+			items = append(items, newItem(bookmark))
+		}
+		user, _ := c.User()
+		return cacheMsg{folderID: folderID, items: items, folders: folders, user: user}
+	}
+}
+
+// syncList syncs the bookmarks of a folder, stores them in the cache and lists them.
+// Read progress queued while offline is sent first so the sync returns the updated bookmarks.
+func syncList(ctx context.Context, client instapaper.Client, library *instapaper.Library, c *cache.Cache) tea.Cmd {
+	return func() tea.Msg {
+		// if this fails the sync fails as well
+		_ = sendPendingProgress(ctx, client, c)
+		result, err := library.Sync(ctx)
+		if ctx.Err() != nil {
+			// a newer sync replaced this one or the app is quitting
+			return nil
+		}
+		if err != nil {
+			if errors.Is(err, instapaper.ErrUnauthorized) {
+				// the token was revoked, a new login is needed. The queued progress is kept
+				// in case it is the same account.
+				return loginErrMsg{errors.Join(err, tokenstore.Delete(), c.Clear())}
+			}
+			return syncErrMsg{
+				kind:  syncNotification,
+				err:   fmt.Errorf("failed to get bookmarks: %w", err),
+				retry: func(m *model) tea.Cmd { return m.sync() },
+			}
+		}
+		// the cache is best effort, the bookmarks are synced again on the next run anyway
+		_ = c.SaveBookmarks(library.FolderID(), library.Bookmarks())
+		for _, id := range result.Deleted {
+			_ = c.Remove(id)
+		}
+		return initListMsg{folderID: library.FolderID(), items: libraryItems(library)}
+	}
+
+}
+
+// prefetchTexts downloads the articles that are not cached yet so they can be read offline
+func prefetchTexts(ctx context.Context, client instapaper.Client, c *cache.Cache, bookmarks []instapaper.Bookmark) tea.Cmd {
+	return func() tea.Msg {
+		for _, bookmark := range bookmarks {
+			if c.HasText(bookmark) {
+				continue
+			}
+			text, err := client.GetBookmarkText(ctx, bookmark.BookmarkID)
+			if err != nil {
+				// most likely offline or quitting, try again after the next sync
+				return nil
 			}
-			title := bookmark.Title
-			description := fmt.Sprintf("%s | %.0f%%", strings.Join(tagNames, ","), bookmark.Progress*100)
-			items = append(items, item{title: title, desc: description, tags: bookmark.Tags})
+			_ = c.SaveText(bookmark, text)
 		}
-		return initListMsg(items)
+		return nil
 	}
+}
 
+func libraryItems(library *instapaper.Library) []list.Item {
+	items := []list.Item{}
+	for _, bookmark := range library.Bookmarks() {
+		items = append(items, newItem(bookmark))
+	}
+	return items
+}
+
+func newItem(bookmark instapaper.Bookmark) item {
+	tagNames := []string{}
+	for _, tag := range bookmark.Tags {
+		tagNames = append(tagNames, tag.Name)
+	}
+	title := bookmark.Title
+	if bookmark.Starred == "1" {
+		title = "★ " + title
+	}
+	description := fmt.Sprintf("%s | %.0f%%", strings.Join(tagNames, ","), bookmark.Progress*100)
+	return item{bookmark: bookmark, title: title, desc: description, tags: bookmark.Tags}
+}
+
+type initFoldersMsg []folder
+
+func initFolders(ctx context.Context, client instapaper.Client, c *cache.Cache) tea.Cmd {
+	return func() tea.Msg {
+		userFolders, err := client.ListFolders(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return syncErrMsg{
+				kind:  foldersNotification,
+				err:   fmt.Errorf("failed to get folders: %w", err),
+				retry: func(m *model) tea.Cmd { return initFolders(m.ctx, m.client, m.cache) },
+			}
+		}
+		_ = c.SaveFolders(userFolders)
+		folders := append([]folder{}, defaultFolders...)
+		for _, f := range userFolders {
+			folders = append(folders, folder{id: f.ID(), title: f.Title})
+		}
+		return initFoldersMsg(folders)
+	}
 }
 
 type model struct {
-	list  list.Model
-	table table.Model
-	help  help.Model
-	state sessionState
+	// ctx is canceled when quitting, which stops the requests in flight
+	ctx    context.Context
+	cancel context.CancelFunc
+	// syncCancel stops the running sync when a new one starts
+	syncCancel  context.CancelFunc
+	syncing     bool // a sync or the next page is being loaded
+	lastSync    time.Time
+	client      instapaper.Client
+	cache       *cache.Cache
+	syncErr     error // last sync error, the cached data is shown until the next successful sync
+	ready       bool  // set once the client is initialized
+	list        list.Model
+	table       table.Model
+	folderTable table.Model
+	folders     []folder
+	folderID    string
+	// user is the account shown in the status bar, pendingCount the queued read progress updates
+	// and bookmarkCount the synced bookmarks of the listed folder, see countStatus
+	user          instapaper.User
+	pendingCount  int
+	bookmarkCount int
+	moreBookmarks bool
+	// libraries hold the synced bookmarks of each visited folder
+	libraries  map[string]*instapaper.Library
+	tagFilter  tagFilter
+	highlights highlightsModel
+	// pending are the actions sent to the API by bookmark ID, to roll back the list if they fail
+	pending map[int64]pendingAction
+	// notifications are the errors to show, oldest first
+	notifications []notification
+	// confirmDelete is the item to delete once the user confirms, moving the item
+	// to move to the folder picked in the folders table
+	confirmDelete *item
+	moving        *item
+	// highlightsReturn is the view to go back to from the highlights
+	highlightsReturn sessionState
+	reader           readerModel
+	addBookmark      addBookmarkModel
+	tagEditor        tagEditorModel
+	login            loginModel
+	help             help.Model
+	state            sessionState
 }
 
 func (m model) FullHelp() [][]key.Binding {
-	if m.state == bookmarksView {
-		return m.list.FullHelp()
-	} else {
-		return m.table.KeyMap.FullHelp()
+	switch m.state {
+	case bookmarksView:
+		if m.confirmDelete != nil {
+			return [][]key.Binding{{actionKeys.confirm, actionKeys.cancel}}
+		}
+		return append(m.list.FullHelp(),
+			[]key.Binding{keys.read, keys.highlights, keys.add, keys.refresh, tagKeys.edit},
+			[]key.Binding{actionKeys.archive, actionKeys.star, actionKeys.move, actionKeys.delete})
+	case highlightsView:
+		return m.highlights.FullHelp()
+	case readerView:
+		return m.reader.FullHelp()
+	case loginView:
+		return m.login.FullHelp()
+	case addBookmarkView:
+		return m.addBookmark.FullHelp()
+	case tagEditorView:
+		return m.tagEditor.FullHelp()
+	case foldersView:
+		if m.moving != nil {
+			return append(m.folderTable.KeyMap.FullHelp(), []key.Binding{actionKeys.moveHere, actionKeys.cancel})
+		}
+		return append(m.folderTable.KeyMap.FullHelp(), []key.Binding{keys.openFolder})
+	default:
+		return append(m.table.KeyMap.FullHelp(), []key.Binding{tagKeys.toggle, tagKeys.matchMode, tagKeys.clear, tagKeys.rename})
 	}
 }
 
 func (m model) ShortHelp() []key.Binding {
-	if m.state == bookmarksView {
-		return m.list.ShortHelp()
-	} else {
-		return m.table.KeyMap.ShortHelp()
+	switch m.state {
+	case bookmarksView:
+		if m.confirmDelete != nil {
+			return []key.Binding{actionKeys.confirm, actionKeys.cancel}
+		}
+		bindings := append(m.list.ShortHelp(), keys.read, keys.add, actionKeys.archive, actionKeys.star, actionKeys.move, actionKeys.delete)
+		if m.tagFilter.active() {
+			bindings = append(bindings, tagKeys.clear)
+		}
+		return bindings
+	case highlightsView:
+		return m.highlights.ShortHelp()
+	case readerView:
+		return m.reader.ShortHelp()
+	case loginView:
+		return m.login.ShortHelp()
+	case addBookmarkView:
+		return m.addBookmark.ShortHelp()
+	case tagEditorView:
+		return m.tagEditor.ShortHelp()
+	case foldersView:
+		if m.moving != nil {
+			return append(m.folderTable.KeyMap.ShortHelp(), actionKeys.moveHere, actionKeys.cancel)
+		}
+		return append(m.folderTable.KeyMap.ShortHelp(), keys.openFolder)
+	default:
+		return append(m.table.KeyMap.ShortHelp(), tagKeys.toggle, tagKeys.matchMode, tagKeys.clear, tagKeys.rename)
 	}
 }
 
 func (m model) Init() tea.Cmd {
-	return initList()
+	return tea.Batch(initClient(m.ctx), loadCache(m.cache, m.folderID))
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -123,30 +412,31 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	cmds := []tea.Cmd{}
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, m.quit()
+		}
+		if cmd, ok := m.updateNotifications(msg); ok {
+			return m, cmd
+		}
+		// text inputs get all keys
+		if m.capturesInput() {
+			return m.updateCurrentView(msg)
+		}
 		switch msg.String() {
-		case "ctrl+c", "q":
-			return m, tea.Quit
+		case "q":
+			return m, m.quit()
 		case "tab":
-			// switch focus between bookmarks and tags view
-			if m.state == bookmarksView {
+			// cycle focus between bookmarks, tags and folders view
+			switch m.state {
+			case bookmarksView:
 				m.state = tagsView
-			} else {
+			case tagsView:
+				m.state = foldersView
+			case foldersView:
 				m.state = bookmarksView
 			}
 		}
-		// pass msg to the current view
-		switch m.state {
-		case bookmarksView:
-			m.list, cmd = m.list.Update(msg)
-			cmds = append(cmds, cmd)
-			listStyle = listStyle.BorderForeground(lipgloss.Color("5"))
-			tagsStyle = tagsStyle.BorderForeground(lipgloss.Color("0"))
-		case tagsView:
-			m.table, cmd = m.table.Update(msg)
-			cmds = append(cmds, cmd)
-			listStyle = listStyle.BorderForeground(lipgloss.Color("0"))
-			tagsStyle = tagsStyle.BorderForeground(lipgloss.Color("5"))
-		}
+		return m.updateCurrentView(msg)
 	case tea.WindowSizeMsg:
 		// TODO: Find a better way to calculate the sizes for a responsive layout
 		// to properly make the outer border fit the terminal window we need to subtract the
@@ -187,104 +477,567 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		// tags view wip
 		w := msg.Width - lH - 2
-		listStyle = listStyle.Width((w * 2) / 3).Height(msg.Height - lV)
-		tagsStyle = tagsStyle.Width(w / 3).Height(msg.Height - lV)
-		v := outerStyle.GetVerticalFrameSize() + listStyle.GetVerticalFrameSize() + helpStyle.GetVerticalFrameSize() + 5
+		// the status bar takes a line
+		h := msg.Height - lV - 1
+		// the right column stacks the folders above the tags, subtract 2 for the extra border lines
+		fh := h / 3
+		th := h - fh - 2
+		listStyle = listStyle.Width((w * 2) / 3).Height(h)
+		foldersStyle = foldersStyle.Width(w / 3).Height(fh)
+		tagsStyle = tagsStyle.Width(w / 3).Height(th)
+		v := outerStyle.GetVerticalFrameSize() + listStyle.GetVerticalFrameSize() + helpStyle.GetVerticalFrameSize() + 5 + 1
 		m.list.SetSize((w*2/3)-10, msg.Height-v)
 		m.table.SetWidth((w / 3) - 5)
-		m.table.SetHeight(msg.Height - v - 1)
-		m.table.SetColumns([]table.Column{
+		m.table.SetHeight(th)
+		m.table.SetColumns(tagColumns((w / 3) - 5))
+		m.folderTable.SetWidth((w / 3) - 5)
+		m.folderTable.SetHeight(fh)
+		m.folderTable.SetColumns([]table.Column{
 			{Width: (w / 3) - 5},
 		})
+		// the highlights view takes the place of both columns
+		hw := listStyle.GetWidth() + tagsStyle.GetWidth() + 2
+		highlightsStyle = highlightsStyle.Width(hw).Height(h)
+		m.highlights.setSize(hw-2, h)
+		loginStyle = loginStyle.Width(hw).Height(h)
+		m.login.setSize(hw, h)
+		addBookmarkStyle = addBookmarkStyle.Width(hw).Height(h)
+		m.addBookmark.setSize(hw, h)
+		m.tagEditor.setSize(hw, h)
+		readerStyle = readerStyle.Width(hw).Height(h)
+		cmds = append(cmds, m.reader.setSize(hw, h))
+	case initClientMsg:
+		m.client = msg.client
+		m.ready = true
+		m.state = bookmarksView
+		// drop libraries that were created with a previous client
+		m.libraries = map[string]*instapaper.Library{}
+		if msg.accountChanged {
+			cmds = append(cmds, m.forgetAccount())
+		}
+		cmds = append(cmds, initFolders(m.ctx, m.client, m.cache), verifyCredentials(m.ctx, m.client, m.cache), m.sync())
 	case initListMsg:
-		cmd = m.list.SetItems(msg)
+		if msg.folderID != m.folderID {
+			// the user switched folders while syncing
+			break
+		}
+		m.syncing = false
+		m.dismiss(syncNotification)
+		m.lastSync = time.Now()
+		m.updatePendingCount()
+		m.updateBookmarkCount()
+		cmd = m.list.SetItems(m.filterItems(msg.items))
 		cmds = append(cmds, cmd)
 		m.table.SetRows(m.getTagRows())
+		m.syncErr = nil
+		if library, ok := m.libraries[msg.folderID]; ok {
+			cmds = append(cmds, prefetchTexts(m.ctx, m.client, m.cache, library.Bookmarks()))
+		}
+		// a short or filtered list may need the next page right away
+		cmds = append(cmds, m.loadMore())
+	case cacheMsg:
+		// only fill in what the first sync hasn't delivered yet
+		if msg.folderID == m.folderID && len(m.list.Items()) == 0 {
+			cmd = m.list.SetItems(m.filterItems(msg.items))
+			cmds = append(cmds, cmd)
+			m.table.SetRows(m.getTagRows())
+		}
+		if len(m.folders) == len(defaultFolders) {
+			m.folders = msg.folders
+			m.folderTable.SetRows(m.getFolderRows())
+		}
+		if m.user.Username == "" {
+			m.user = msg.user
+		}
+		m.updatePendingCount()
+		m.updateBookmarkCount()
+	case syncErrMsg:
+		if msg.kind == syncNotification {
+			m.syncing = false
+			m.syncErr = msg.err
+		}
+		m.notify(msg.kind, msg.err, msg.retry)
+		m.updatePendingCount()
+	case userMsg:
+		m.user = msg.user
+	case initFoldersMsg:
+		m.dismiss(foldersNotification)
+		m.folders = msg
+		m.folderTable.SetRows(m.getFolderRows())
+	case showLoginMsg:
+		m.state = loginView
+		m.login, cmd = m.login.focus()
+		cmds = append(cmds, cmd)
+	case loginErrMsg:
+		m.state = loginView
+		m.ready = false
+		if errors.Is(msg.err, instapaper.ErrUnauthorized) {
+			// the cache was cleared with the revoked token
+			cmds = append(cmds, m.forgetAccount())
+		}
+		m.login, cmd = m.login.Update(msg)
+		cmds = append(cmds, cmd)
+	case articleMsg, articleErrMsg, spinner.TickMsg, progressTickMsg:
+		m.reader, cmd = m.reader.Update(msg)
+		cmds = append(cmds, cmd)
+	case progressSavedMsg:
+		m.updatePendingCount()
+		m.reader, cmd = m.reader.Update(msg)
+		cmds = append(cmds, cmd, m.updateBookmark(msg.bookmark))
+	case highlightsMsg, highlightCreatedMsg, highlightDeletedMsg, highlightsErrMsg:
+		m.highlights, cmd = m.highlights.Update(msg)
+		cmds = append(cmds, cmd)
+	case actionDoneMsg:
+		cmds = append(cmds, m.finishAction(msg))
+	case actionErrMsg:
+		cmds = append(cmds, m.rollbackAction(msg))
+	case bookmarkAddedMsg:
+		m.state = bookmarksView
+		setFocusStyles(m.state)
+		cmds = append(cmds, m.addToList(msg.bookmark, msg.folderID))
+	case tagsSavedMsg:
+		if m.state == tagEditorView {
+			m.state = m.tagEditorReturn()
+			setFocusStyles(m.state)
+		}
+		if msg.from != "" {
+			m.tagFilter = m.tagFilter.rename(msg.from, msg.to)
+		}
+		if msg.skipped > 0 {
+			m.notify(tagsNotification, fmt.Errorf("%d private bookmark(s) still tagged %q: %w", msg.skipped, msg.from, instapaper.ErrPrivateBookmark), nil)
+		}
+		cmds = append(cmds, m.applyTags(msg.bookmarks))
+	case tagsErrMsg:
+		if m.state == tagEditorView {
+			m.tagEditor, cmd = m.tagEditor.Update(msg)
+			cmds = append(cmds, cmd)
+		} else {
+			// the editor was closed while saving
+			m.notify(tagsNotification, msg.err, nil)
+		}
+		cmds = append(cmds, m.applyTags(msg.bookmarks))
+	default:
+		// clipboardURLMsg, addBookmarkErrMsg and the paste messages of the text inputs
+		switch m.state {
+		case addBookmarkView:
+			m.addBookmark, cmd = m.addBookmark.Update(msg)
+			cmds = append(cmds, cmd)
+		case tagEditorView:
+			m.tagEditor, cmd = m.tagEditor.Update(msg)
+			cmds = append(cmds, cmd)
+		}
 	}
 
 	return m, tea.Batch(cmds...)
 }
 
+// updateCurrentView passes a key msg to the focused view
+func (m model) updateCurrentView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	cmds := []tea.Cmd{}
+	switch m.state {
+	case bookmarksView:
+		if m.list.FilterState() == list.Filtering {
+			m.list, cmd = m.list.Update(msg)
+			cmds = append(cmds, cmd)
+			break
+		}
+		if cmd, ok := m.updateActions(msg); ok {
+			cmds = append(cmds, cmd)
+			break
+		}
+		if key.Matches(msg, tagKeys.clear) && m.tagFilter.active() {
+			cmds = append(cmds, m.setTagFilter(tagFilter{any: m.tagFilter.any}))
+			break
+		}
+		if key.Matches(msg, keys.read) {
+			if i, ok := m.list.SelectedItem().(item); ok {
+				m.state = readerView
+				m.reader, cmd = m.reader.open(m.ctx, m.client, m.cache, i.bookmark)
+				cmds = append(cmds, cmd)
+			}
+			break
+		}
+		if key.Matches(msg, keys.highlights) {
+			if i, ok := m.list.SelectedItem().(item); ok {
+				m.state = highlightsView
+				m.highlightsReturn = bookmarksView
+				m.highlights, cmd = m.highlights.open(m.ctx, m.client, m.cache, i.bookmark)
+				cmds = append(cmds, cmd)
+			}
+			break
+		}
+		if key.Matches(msg, keys.refresh) && m.ready {
+			cmds = append(cmds, m.sync())
+			break
+		}
+		if key.Matches(msg, tagKeys.edit) && m.ready {
+			if i, ok := m.list.SelectedItem().(item); ok && i.bookmark.URL == "" {
+				m.notify(tagsNotification, fmt.Errorf("cannot edit the tags of %q: %w", i.Title(), instapaper.ErrPrivateBookmark), nil)
+			} else if ok {
+				m.state = tagEditorView
+				m.tagEditor, cmd = m.tagEditor.editBookmark(m.ctx, m.client, i.bookmark, m.bookmarkFolder(i.ID()), m.tagNames())
+				cmds = append(cmds, cmd)
+			}
+			break
+		}
+		if key.Matches(msg, keys.add) && m.ready {
+			m.state = addBookmarkView
+			m.addBookmark, cmd = m.addBookmark.open(m.ctx, m.client, m.folders[len(defaultFolders):], m.folderID)
+			cmds = append(cmds, cmd)
+			break
+		}
+		m.list, cmd = m.list.Update(msg)
+		cmds = append(cmds, cmd, m.loadMore())
+	case loginView:
+		m.login, cmd = m.login.Update(msg)
+		cmds = append(cmds, cmd)
+	case addBookmarkView:
+		if key.Matches(msg, addBookmarkKeys.cancel) {
+			m.state = bookmarksView
+			break
+		}
+		m.addBookmark, cmd = m.addBookmark.Update(msg)
+		cmds = append(cmds, cmd)
+	case tagEditorView:
+		if key.Matches(msg, tagEditorKeys.cancel) {
+			m.state = m.tagEditorReturn()
+			break
+		}
+		m.tagEditor, cmd = m.tagEditor.Update(msg)
+		cmds = append(cmds, cmd)
+	case highlightsView:
+		if key.Matches(msg, highlightKeys.back) && !m.highlights.capturesInput() &&
+			m.highlights.list.FilterState() == list.Unfiltered {
+			m.state = m.highlightsReturn
+			m.highlights.close()
+			break
+		}
+		m.highlights, cmd = m.highlights.Update(msg)
+		cmds = append(cmds, cmd)
+	case readerView:
+		switch {
+		case key.Matches(msg, readerKeys.back):
+			m.state = bookmarksView
+			m.reader.close()
+			cmds = append(cmds, m.reader.saveProgress())
+		case key.Matches(msg, keys.highlights), key.Matches(msg, readerKeys.highlight) && m.reader.markdown != "":
+			m.state = highlightsView
+			m.highlightsReturn = readerView
+			m.highlights, cmd = m.highlights.open(m.ctx, m.client, m.cache, m.reader.bookmark)
+			cmds = append(cmds, cmd)
+			if key.Matches(msg, readerKeys.highlight) {
+				// start a new highlight with the text at the top of the reader, to be edited before saving
+				m.highlights, cmd = m.highlights.startAdding(m.reader.topLine())
+				cmds = append(cmds, cmd)
+			}
+		default:
+			m.reader, cmd = m.reader.Update(msg)
+			cmds = append(cmds, cmd)
+		}
+	case tagsView:
+		cmds = append(cmds, m.updateTags(msg))
+	case foldersView:
+		if m.moving != nil {
+			switch {
+			case key.Matches(msg, actionKeys.moveHere) && m.folderTable.Cursor() < len(m.folders):
+				cmds = append(cmds, m.moveTo(m.folders[m.folderTable.Cursor()]))
+			case key.Matches(msg, actionKeys.cancel):
+				m.moving = nil
+				m.state = bookmarksView
+			default:
+				m.folderTable, cmd = m.folderTable.Update(msg)
+				cmds = append(cmds, cmd)
+			}
+			break
+		}
+		if key.Matches(msg, keys.openFolder) && m.ready && m.folderTable.Cursor() < len(m.folders) {
+			m.folderID = m.folders[m.folderTable.Cursor()].id
+			m.folderTable.SetRows(m.getFolderRows())
+			// the tags of the previous folder may not be used in this one
+			m.tagFilter = tagFilter{any: m.tagFilter.any}
+			// show what we have from previous syncs right away
+			library := m.library()
+			m.updateBookmarkCount()
+			cmd = m.list.SetItems(libraryItems(library))
+			m.table.SetRows(m.getTagRows())
+			cmds = append(cmds, cmd, m.sync())
+			break
+		}
+		m.folderTable, cmd = m.folderTable.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+	setFocusStyles(m.state)
+	return m, tea.Batch(cmds...)
+}
+
+// capturesInput reports whether the focused view is taking text input
+func (m model) capturesInput() bool {
+	switch m.state {
+	case bookmarksView:
+		return m.list.FilterState() == list.Filtering || m.confirmDelete != nil
+	case foldersView:
+		// picking the folder to move an item to
+		return m.moving != nil
+	case highlightsView:
+		return m.highlights.capturesInput()
+	case loginView, addBookmarkView, tagEditorView:
+		return true
+	}
+	return false
+}
+
 func (m model) View() string {
 	// return listStyle.Render(m.list.View())
-	listWithTagsView := lipgloss.JoinHorizontal(
-		lipgloss.Bottom,
-		listStyle.Render(m.list.View()),
-		tagsStyle.Render(m.table.View()),
-	)
+	var mainView string
+	switch m.state {
+	case highlightsView:
+		mainView = highlightsStyle.Render(m.highlights.View())
+	case loginView:
+		mainView = loginStyle.Render(m.login.View())
+	case readerView:
+		mainView = readerStyle.Render(m.reader.View())
+	case addBookmarkView:
+		mainView = addBookmarkStyle.Render(m.addBookmark.View())
+	case tagEditorView:
+		mainView = addBookmarkStyle.Render(m.tagEditor.View())
+	default:
+		mainView = m.browseView()
+	}
+	// the help is cut off so the status fits on the same line
+	status := m.tagStatus() + m.actionStatus() + m.syncStatus()
+	m.help.Width = max(helpStyle.GetWidth()-lipgloss.Width(status), 0)
+	helpView := helpStyle.Render(m.help.View(m) + status)
+	statusBar := m.statusBar(lipgloss.Width(helpView))
+	if len(m.notifications) > 0 {
+		statusBar = m.notificationBar(lipgloss.Width(helpView))
+	}
 	view := lipgloss.JoinVertical(
 		lipgloss.Bottom,
-		listWithTagsView,
-		helpStyle.Render(m.help.View(m)),
+		mainView,
+		statusBar,
+		helpView,
 	)
 	return outerStyle.Render(view)
 }
 
+// browseView shows the bookmarks list next to the folders and tags
+func (m model) browseView() string {
+	return lipgloss.JoinHorizontal(
+		lipgloss.Bottom,
+		listStyle.Render(m.list.View()),
+		lipgloss.JoinVertical(
+			lipgloss.Left,
+			foldersStyle.Render(m.folderTable.View()),
+			tagsStyle.Render(m.table.View()),
+		),
+	)
+}
+
 // misc helper functions
 
-// getTags returns a map of tags and their counts from the list of downloaded bookmarks
-// the current version of the instapaper api does not support fetching tags
-func (m model) getTags() map[string]int {
-	tags := map[string]int{}
-	for _, i := range m.list.Items() {
-		for _, tag := range i.(item).Tags() {
-			tags[tag.Name]++
+// quit saves the read progress if the reader is open and exits
+func (m *model) quit() tea.Cmd {
+	// saving the progress is not affected, see readerModel.saveProgress
+	m.cancel()
+	if m.state == readerView {
+		return tea.Sequence(m.reader.saveProgress(), tea.Quit)
+	}
+	return tea.Quit
+}
+
+// updateBookmark replaces a bookmark that changed locally in the libraries and the list
+func (m *model) updateBookmark(bookmark instapaper.Bookmark) tea.Cmd {
+	for _, library := range m.libraries {
+		library.Update(bookmark)
+	}
+	for i, li := range m.list.Items() {
+		if li.(item).ID() == bookmark.BookmarkID {
+			return m.list.SetItem(i, newItem(bookmark))
 		}
 	}
-	return tags
+	return nil
 }
 
-// todo: cleanup and move elsewhere
-type tagKeyValue struct {
-	key   string
-	value int
+// addToList puts a saved bookmark into the library of its folder, and at the top of the
+// list if the folder is listed. Saving a URL that is already bookmarked updates the item.
+func (m *model) addToList(bookmark instapaper.Bookmark, folderID string) tea.Cmd {
+	if library, ok := m.libraries[folderID]; ok {
+		library.Add(bookmark)
+		_ = m.cache.SaveBookmarks(folderID, library.Bookmarks())
+	}
+	m.updateBookmarkCount()
+	if folderID != m.folderID || !m.tagFilter.matches(newItem(bookmark)) {
+		return nil
+	}
+	for i, li := range m.list.Items() {
+		if li.(item).ID() == bookmark.BookmarkID {
+			return m.list.SetItem(i, newItem(bookmark))
+		}
+	}
+	cmd := m.list.InsertItem(0, newItem(bookmark))
+	m.list.Select(0)
+	m.table.SetRows(m.getTagRows())
+	return cmd
+}
+
+// forgetAccount drops the bookmarks, folders and user shown for the previous account
+func (m *model) forgetAccount() tea.Cmd {
+	m.user = instapaper.User{}
+	m.libraries = map[string]*instapaper.Library{}
+	m.folders = defaultFolders
+	m.folderID = instapaper.FolderUnread
+	m.folderTable.SetRows(m.getFolderRows())
+	m.folderTable.SetCursor(0)
+	m.tagFilter = tagFilter{any: m.tagFilter.any}
+	m.notifications = nil
+	m.syncErr = nil
+	m.lastSync = time.Time{}
+	m.updatePendingCount()
+	m.updateBookmarkCount()
+	cmd := m.list.SetItems(nil)
+	m.table.SetRows(m.getTagRows())
+	return cmd
+}
+
+// sync cancels the running sync and starts syncing the current folder
+func (m *model) sync() tea.Cmd {
+	if m.syncCancel != nil {
+		m.syncCancel()
+	}
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.syncCancel = cancel
+	m.syncing = true
+	return syncList(ctx, m.client, m.library(), m.cache)
 }
 
-func (m model) getTagRows() []table.Row {
-	tags := m.getTags()
-	// sort by count
-	kv := make([]tagKeyValue, 0, len(tags))
-	for k, v := range tags {
-		kv = append(kv, tagKeyValue{k, v})
+// loadMore loads the next page of bookmarks when the cursor is close to the end of the list.
+// Syncing excludes the bookmarks that are already loaded, so it returns the next page.
+func (m *model) loadMore() tea.Cmd {
+	if !m.ready || m.syncing || m.library().Complete() {
+		return nil
 	}
-	sort.Slice(kv, func(i, j int) bool {
-		return kv[i].value > kv[j].value
-	})
-	items := []table.Row{}
-	for _, tag := range kv {
-		items = append(items, table.Row{fmt.Sprintf("(%d) %s", tag.value, tag.key)})
+	if m.list.Index() < len(m.list.VisibleItems())-loadMoreThreshold {
+		return nil
 	}
+	return m.sync()
+}
 
-	return items
+// library returns the library of the current folder, creating it from the cache on first use
+func (m model) library() *instapaper.Library {
+	library, ok := m.libraries[m.folderID]
+	if !ok {
+		cached, _ := m.cache.Bookmarks(m.folderID)
+		library = instapaper.NewLibrary(m.client, m.folderID, bookmarkLimit, cached)
+		m.libraries[m.folderID] = library
+	}
+	return library
 }
 
-// main function, inits and runs the tea
-func main() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
+// syncStatus tells the user that cached data is shown after a failed sync
+func (m model) syncStatus() string {
+	if m.syncErr == nil || m.state == loginView {
+		return ""
 	}
+	if errors.Is(m.syncErr, instapaper.ErrRateLimited) {
+		return errStyle.Render(" • rate limited, showing cached bookmarks")
+	}
+	return errStyle.Render(" • offline, showing cached bookmarks")
+}
+
+func (m model) getFolderRows() []table.Row {
+	rows := []table.Row{}
+	for _, f := range m.folders {
+		// mark the folder that is currently listed
+		prefix := "  "
+		if f.id == m.folderID {
+			prefix = "> "
+		}
+		rows = append(rows, table.Row{prefix + f.title})
+	}
+	return rows
+}
 
+// setFocusStyles highlights the border of the focused view
+func setFocusStyles(state sessionState) {
+	listStyle = listStyle.BorderForeground(lipgloss.Color("0"))
+	tagsStyle = tagsStyle.BorderForeground(lipgloss.Color("0"))
+	foldersStyle = foldersStyle.BorderForeground(lipgloss.Color("0"))
+	switch state {
+	case bookmarksView:
+		listStyle = listStyle.BorderForeground(lipgloss.Color("5"))
+	case tagsView:
+		tagsStyle = tagsStyle.BorderForeground(lipgloss.Color("5"))
+	case foldersView:
+		foldersStyle = foldersStyle.BorderForeground(lipgloss.Color("5"))
+	}
+}
+
+func newModel(c *cache.Cache) model {
 	columns := []table.Column{
 		{Width: 10},
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
 	m := model{
-		state: bookmarksView,
-		list:  list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0),
-		help:  help.New(),
+		ctx:         ctx,
+		cancel:      cancel,
+		cache:       c,
+		state:       bookmarksView,
+		folderID:    instapaper.FolderUnread,
+		folders:     defaultFolders,
+		libraries:   map[string]*instapaper.Library{},
+		pending:     map[int64]pendingAction{},
+		list:        list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0),
+		highlights:  newHighlightsModel(),
+		reader:      newReaderModel(),
+		addBookmark: newAddBookmarkModel(),
+		tagEditor:   newTagEditorModel(),
+		login:       newLoginModel(ctx, c),
+		help:        help.New(),
 		table: table.New(
 			table.WithFocused(true),
-			table.WithColumns(columns),
+			table.WithColumns(tagColumns(20)),
 			table.WithHeight(5),
 			table.WithRows(
-				[]table.Row{{"Loading..."}})),
+				[]table.Row{{tagNameColumn: "Loading..."}})),
+		folderTable: table.New(
+			table.WithFocused(true),
+			table.WithColumns(columns),
+			table.WithHeight(5)),
 	}
+	m.folderTable.SetRows(m.getFolderRows())
 	// m.list.Title = "My Instapaper list"
 	m.list.SetShowTitle(false)
 	m.list.SetShowStatusBar(false)
 	m.list.SetShowHelp(false)
-	p := tea.NewProgram(m, tea.WithAltScreen())
+	return m
+}
+
+// main function, inits and runs the tea
+func main() {
+	// the .env file is optional, the token store replaced the credentials in it
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Fatalf("Error loading .env file: %v", err)
+	}
+
+	flag.DurationVar(&timeout, "timeout", 0, "")
+	flag.Var(&output, "output", "")
+	flag.Var(&output, "o", "")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+	if flag.NArg() > 0 {
+		if err := runCommand(flag.Args()); err != nil {
+			log.Fatalf("Error: %v\n", err)
+		}
+		return
+	}
+
+	c, err := cache.Open()
+	if err != nil {
+		log.Fatalf("Error: %v\n", err)
+	}
+	p := tea.NewProgram(newModel(c), tea.WithAltScreen())
 
 	if _, err := p.Run(); err != nil {
 		log.Fatalf("Error: %v\n", err)
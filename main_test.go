@@ -0,0 +1,777 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ieroNo47/gopaper/internal/cache"
+	"github.com/ieroNo47/gopaper/internal/instapaper"
+	"github.com/ieroNo47/gopaper/internal/instapaper/fakeserver"
+	"github.com/ieroNo47/gopaper/internal/tokenstore"
+)
+
+// cmdTimeout is how long drive waits for a command, it has to allow for the retries
+// of the client. Ticks that take longer are dropped.
+const cmdTimeout = 5 * time.Second
+
+// testEnv points the app at a fake server and isolates the token store
+func testEnv(t *testing.T) (*fakeserver.Server, *httptest.Server) {
+	t.Helper()
+	srv := fakeserver.New()
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("IP_API", ts.URL+fakeserver.BasePath)
+	t.Setenv("IP_API_VERSION", "")
+	t.Setenv("IP_OAUTH_CONSUMER_ID", fakeserver.ConsumerKey)
+	t.Setenv("IP_OAUTH_CONSUMER_SECRET", fakeserver.ConsumerSecret)
+	t.Setenv("IP_USER", "")
+	t.Setenv("IP_PASSWORD", "")
+	return srv, ts
+}
+
+// start creates a model like main does and runs its Init command
+func start(t *testing.T, c *cache.Cache) model {
+	t.Helper()
+	m := newModel(c)
+	m = drive(t, m, func() tea.Msg { return tea.WindowSizeMsg{Width: 120, Height: 40} })
+	return drive(t, m, m.Init())
+}
+
+// drive runs cmd and feeds the resulting messages into the model, and the commands
+// those return, until there is nothing left to do. Like in a tea.Program, commands run
+// concurrently and the commands of a sequence one after the other.
+func drive(t *testing.T, m model, cmd tea.Cmd) model {
+	t.Helper()
+	msgs := make(chan tea.Msg)
+	pending := 0
+	run := func(cmds ...tea.Cmd) {
+		pending += len(cmds)
+		go func() {
+			for _, cmd := range cmds {
+				msgs <- runCmd(cmd)
+			}
+		}()
+	}
+	run(cmd)
+	for pending > 0 {
+		msg := <-msgs
+		pending--
+		switch msg := msg.(type) {
+		case nil, tea.QuitMsg, spinner.TickMsg, cursor.BlinkMsg:
+			// the spinner ticks and the cursor blinks forever
+			continue
+		case tea.BatchMsg:
+			for _, cmd := range msg {
+				run(cmd)
+			}
+			continue
+		}
+		// tea.Sequence returns an unexported slice of commands
+		if v := reflect.ValueOf(msg); v.Kind() == reflect.Slice && v.Type().Elem() == reflect.TypeOf(tea.Cmd(nil)) {
+			cmds := []tea.Cmd{}
+			for i := range v.Len() {
+				cmds = append(cmds, v.Index(i).Interface().(tea.Cmd))
+			}
+			run(cmds...)
+			continue
+		}
+
+		next, cmd := m.Update(msg)
+		m = next.(model)
+		run(cmd)
+	}
+	return m
+}
+
+// runCmd runs a command, giving up after cmdTimeout
+func runCmd(cmd tea.Cmd) tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	done := make(chan tea.Msg, 1)
+	go func() { done <- cmd() }()
+	select {
+	case msg := <-done:
+		return msg
+	case <-time.After(cmdTimeout):
+		return nil
+	}
+}
+
+func press(t *testing.T, m model, keys ...string) model {
+	t.Helper()
+	for _, k := range keys {
+		var msg tea.KeyMsg
+		switch k {
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "esc":
+			msg = tea.KeyMsg{Type: tea.KeyEsc}
+		case "tab":
+			msg = tea.KeyMsg{Type: tea.KeyTab}
+		case "down":
+			msg = tea.KeyMsg{Type: tea.KeyDown}
+		case "ctrl+r":
+			msg = tea.KeyMsg{Type: tea.KeyCtrlR}
+		case "ctrl+x":
+			msg = tea.KeyMsg{Type: tea.KeyCtrlX}
+		default:
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		}
+		m = drive(t, m, func() tea.Msg { return msg })
+	}
+	return m
+}
+
+func listedIDs(m model) []int64 {
+	ids := []int64{}
+	for _, li := range m.list.Items() {
+		ids = append(ids, li.(item).ID())
+	}
+	return ids
+}
+
+var unreadIDs = []int64{fakeserver.BookmarkSpec, fakeserver.BookmarkBubbleTea, fakeserver.BookmarkOAuth}
+
+func TestModelSyncsBookmarks(t *testing.T) {
+	srv, _ := testEnv(t)
+	if err := tokenstore.Save(srv.IssueToken()); err != nil {
+		t.Fatal(err)
+	}
+	c := cache.New(t.TempDir())
+
+	m := start(t, c)
+	if !m.ready || m.state != bookmarksView {
+		t.Fatalf("model is not ready after Init, state %v", m.state)
+	}
+	if got := listedIDs(m); !slices.Equal(got, unreadIDs) {
+		t.Errorf("listed bookmarks = %v, want %v", got, unreadIDs)
+	}
+	folders := []string{}
+	for _, f := range m.folders {
+		folders = append(folders, f.title)
+	}
+	if want := []string{"Home", "Starred", "Archive", "Tech", "Recipes"}; !slices.Equal(folders, want) {
+		t.Errorf("folders = %v, want %v", folders, want)
+	}
+	if len(m.table.Rows()) != 2 {
+		t.Errorf("tag rows = %v, want go and tui", m.table.Rows())
+	}
+	cached, err := c.Bookmarks(instapaper.FolderUnread)
+	if err != nil || len(cached) != len(unreadIDs) {
+		t.Errorf("cached %d bookmarks, err %v, want %d", len(cached), err, len(unreadIDs))
+	}
+	if !strings.Contains(m.View(), "The Go Programming Language Specification") {
+		t.Error("the view does not show the first bookmark")
+	}
+}
+
+func TestModelLogin(t *testing.T) {
+	testEnv(t)
+	// the cache of another account is cleared once the login confirms the account
+	c := cache.New(t.TempDir())
+	if err := c.SaveUser(instapaper.User{UserID: 7, Username: "previous"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SaveBookmarks("1234", []instapaper.Bookmark{{BookmarkID: 1, Title: "Previous"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.QueueProgress(cache.PendingProgress{BookmarkID: 1, Progress: 0.5, Timestamp: 1}); err != nil {
+		t.Fatal(err)
+	}
+	m := start(t, c)
+	if m.state != loginView {
+		t.Fatalf("state = %v without a token, want the login view", m.state)
+	}
+
+	m = press(t, m, fakeserver.Username, "enter", "wrong", "enter")
+	if m.state != loginView || m.login.err == nil {
+		t.Fatalf("state = %v, err %v after a wrong password, want a login error", m.state, m.login.err)
+	}
+
+	m = press(t, m, fakeserver.Password, "enter")
+	if m.state != bookmarksView || !m.ready {
+		t.Fatalf("state = %v after logging in, err %v", m.state, m.login.err)
+	}
+	if _, err := tokenstore.Load(); err != nil {
+		t.Errorf("token was not stored: %v", err)
+	}
+	if got := listedIDs(m); !slices.Equal(got, unreadIDs) {
+		t.Errorf("listed bookmarks = %v, want %v", got, unreadIDs)
+	}
+	if bookmarks, err := c.Bookmarks("1234"); err == nil {
+		t.Errorf("cached bookmarks of the previous account = %v, want none", bookmarks)
+	}
+	if pending, _ := c.PendingProgress(); len(pending) != 0 {
+		t.Errorf("queued progress of the previous account = %v, want none", pending)
+	}
+	if user, _ := c.User(); user.Username != fakeserver.Username {
+		t.Errorf("cached user = %q, want %q", user.Username, fakeserver.Username)
+	}
+}
+
+func TestModelRevokedToken(t *testing.T) {
+	srv, _ := testEnv(t)
+	if err := tokenstore.Save(srv.IssueToken()); err != nil {
+		t.Fatal(err)
+	}
+	srv.RevokeTokens()
+	c := cache.New(t.TempDir())
+	if err := c.SaveBookmarks(instapaper.FolderUnread, []instapaper.Bookmark{{BookmarkID: 1, Title: "Cached"}}); err != nil {
+		t.Fatal(err)
+	}
+	queued := cache.PendingProgress{BookmarkID: fakeserver.BookmarkSpec, Progress: 0.5, Timestamp: 1}
+	if err := c.QueueProgress(queued); err != nil {
+		t.Fatal(err)
+	}
+
+	m := start(t, c)
+	if m.state != loginView {
+		t.Errorf("state = %v with a revoked token, want the login view", m.state)
+	}
+	if _, err := tokenstore.Load(); !errors.Is(err, tokenstore.ErrNotFound) {
+		t.Errorf("revoked token was not deleted: %v", err)
+	}
+	// the next login may be another account
+	if bookmarks, err := c.Bookmarks(instapaper.FolderUnread); err == nil {
+		t.Errorf("cached bookmarks = %v after the token was revoked, want none", bookmarks)
+	}
+	if got := listedIDs(m); len(got) != 0 {
+		t.Errorf("listed bookmarks = %v after the token was revoked, want none", got)
+	}
+	// the progress is sent if the same account logs in again
+	if pending, _ := c.PendingProgress(); !slices.Equal(pending, []cache.PendingProgress{queued}) {
+		t.Errorf("queued progress = %v after the token was revoked, want it kept", pending)
+	}
+}
+
+func TestModelOffline(t *testing.T) {
+	srv, ts := testEnv(t)
+	if err := tokenstore.Save(srv.IssueToken()); err != nil {
+		t.Fatal(err)
+	}
+	c := cache.New(t.TempDir())
+	start(t, c)
+
+	ts.Close()
+	m := start(t, c)
+	if m.syncErr == nil {
+		t.Error("no sync error with the server down")
+	}
+	if got := listedIDs(m); !slices.Equal(got, unreadIDs) {
+		t.Errorf("listed bookmarks = %v, want the cached %v", got, unreadIDs)
+	}
+	if !strings.Contains(m.View(), "offline") {
+		t.Error("the view does not say it is offline")
+	}
+
+	// the sync can be retried once the server is back
+	ts = httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	t.Setenv("IP_API", ts.URL+fakeserver.BasePath)
+	m.client, _ = newCommandClient(context.Background())
+	m.libraries = map[string]*instapaper.Library{}
+	// the bookmarks and the folders failed
+	m = press(t, m, "ctrl+r", "ctrl+r")
+	if m.syncErr != nil || len(m.notifications) != 0 {
+		t.Errorf("sync error %v with %d notifications after retrying, want none", m.syncErr, len(m.notifications))
+	}
+}
+
+// the model lists the cached bookmarks on start, before the first sync
+func TestModelStartsWithCache(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("IP_USER", "")
+	t.Setenv("IP_PASSWORD", "")
+	c := cache.New(t.TempDir())
+	cached := []instapaper.Bookmark{{BookmarkID: 1, Title: "Cached"}}
+	if err := c.SaveBookmarks(instapaper.FolderUnread, cached); err != nil {
+		t.Fatal(err)
+	}
+
+	var m tea.Model = newModel(c)
+	batch, ok := m.Init()().(tea.BatchMsg)
+	if !ok {
+		t.Fatal("Init() did not return a batch of commands")
+	}
+	for _, cmd := range batch {
+		if cmd != nil {
+			m, _ = m.Update(cmd())
+		}
+	}
+	items := m.(model).list.Items()
+	if len(items) != 1 || items[0].FilterValue() != "Cached" {
+		t.Errorf("listed %d bookmarks, want the cached bookmark", len(items))
+	}
+}
+
+func TestModelSwitchFolder(t *testing.T) {
+	srv, _ := testEnv(t)
+	if err := tokenstore.Save(srv.IssueToken()); err != nil {
+		t.Fatal(err)
+	}
+	m := start(t, cache.New(t.TempDir()))
+
+	// Home, Starred, Archive, Tech
+	m = press(t, m, "tab", "tab", "down", "down", "down", "enter")
+	if got, want := listedIDs(m), []int64{fakeserver.BookmarkNoText}; !slices.Equal(got, want) {
+		t.Errorf("listed bookmarks in Tech = %v, want %v", got, want)
+	}
+}
+
+func TestModelReader(t *testing.T) {
+	srv, _ := testEnv(t)
+	if err := tokenstore.Save(srv.IssueToken()); err != nil {
+		t.Fatal(err)
+	}
+	m := start(t, cache.New(t.TempDir()))
+
+	m = press(t, m, "enter")
+	if m.state != readerView {
+		t.Fatalf("state = %v after enter, want the reader", m.state)
+	}
+	if !strings.Contains(m.reader.markdown, "general-purpose language") {
+		t.Errorf("reader markdown = %q, want the article", m.reader.markdown)
+	}
+
+	m = press(t, m, "H")
+	if m.state != highlightsView {
+		t.Fatalf("state = %v after H, want the highlights", m.state)
+	}
+	if n := len(m.highlights.list.Items()); n != 1 {
+		t.Errorf("listed %d highlights, want 1", n)
+	}
+
+	// a blinking cursor waits for the next blink after every key
+	m.highlights.input.Cursor.SetMode(cursor.CursorStatic)
+	m = press(t, m, "n", "strongly typed", "enter")
+	items := m.highlights.list.Items()
+	if len(items) != 2 || m.highlights.err != nil {
+		t.Fatalf("listed %d highlights after adding one, err %v", len(items), m.highlights.err)
+	}
+	before := "The Go Programming Language Specification Go is a general-purpose language designed with systems programming in mind. It is "
+	if got := items[1].(highlightItem).highlight.Position; got != len(before) {
+		t.Errorf("new highlight position = %d, want %d", got, len(before))
+	}
+	m = press(t, m, "down", "x", "n")
+	if n := len(m.highlights.list.Items()); n != 2 {
+		t.Fatalf("listed %d highlights after canceling the delete, want 2", n)
+	}
+	m = press(t, m, "x", "y")
+	if n := len(m.highlights.list.Items()); n != 1 {
+		t.Errorf("listed %d highlights after confirming the delete, want 1", n)
+	}
+	m = press(t, m, "esc")
+	if m.state != readerView {
+		t.Errorf("state = %v after esc, want back to the reader", m.state)
+	}
+}
+
+func TestTextPosition(t *testing.T) {
+	tests := []struct {
+		name, article, text string
+		want                int
+	}{
+		{"paragraphs", "<h1>Title</h1><p>First</p><p>Second one</p>", "First Second", 6},
+		{"inline markup", "<p>An <em>important</em> <a href=\"#\">word</a>.</p>", "important word.", 3},
+		{"within a word", "<p>Go<strong>pher</strong>s</p>", "Gophers", 0},
+		{"line break", "<p>one<br>two</p>", "one two", 0},
+		{"script", "<p>a</p><script>var b</script><p>b</p>", "var", -1},
+		{"missing", "<p>text</p>", "other", -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := textPosition(tt.article, tt.text); got != tt.want {
+				t.Errorf("textPosition(%q, %q) = %d, want %d", tt.article, tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModelQuitCancelsRequests(t *testing.T) {
+	srv, _ := testEnv(t)
+	if err := tokenstore.Save(srv.IssueToken()); err != nil {
+		t.Fatal(err)
+	}
+	m := start(t, cache.New(t.TempDir()))
+	m = press(t, m, "enter")
+	readerCtx := m.reader.ctx
+
+	m = press(t, m, "q")
+	if m.ctx.Err() == nil {
+		t.Error("quitting did not cancel the requests in flight")
+	}
+	if readerCtx.Err() == nil {
+		t.Error("quitting did not cancel the reader")
+	}
+}
+
+func TestModelAddBookmark(t *testing.T) {
+	srv, _ := testEnv(t)
+	if err := tokenstore.Save(srv.IssueToken()); err != nil {
+		t.Fatal(err)
+	}
+	clip := "https://example.com/from-the-clipboard"
+	readClipboard = func() (string, error) { return clip, nil }
+	t.Cleanup(func() { readClipboard = clipboard.ReadAll })
+	m := start(t, cache.New(t.TempDir()))
+	// a blinking cursor waits for the next blink after every key
+	for i := range m.addBookmark.inputs {
+		m.addBookmark.inputs[i].Cursor.SetMode(cursor.CursorStatic)
+	}
+
+	m = press(t, m, "a")
+	if m.state != addBookmarkView {
+		t.Fatalf("state = %v after a, want the add bookmark dialog", m.state)
+	}
+	if got := m.addBookmark.inputs[urlField].Value(); got != clip {
+		t.Errorf("URL = %q, want %q from the clipboard", got, clip)
+	}
+	m = press(t, m, "tab", "Clipped", "enter")
+	if m.state != bookmarksView {
+		t.Fatalf("state = %v after saving, err %v", m.state, m.addBookmark.err)
+	}
+	first, ok := m.list.Items()[0].(item)
+	if !ok || first.bookmark.URL != clip || first.Title() != "Clipped" {
+		t.Errorf("first item = %+v, want the new bookmark", m.list.Items()[0])
+	}
+	if _, _, ok := srv.Bookmark(first.ID()); !ok {
+		t.Error("the bookmark was not saved")
+	}
+	if n := len(m.list.Items()); n != len(unreadIDs)+1 {
+		t.Errorf("listed %d bookmarks, want %d", n, len(unreadIDs)+1)
+	}
+
+	// a bookmark saved to another folder is not listed
+	m = press(t, m, "a", "tab", "tab", "tab", "Recipes", "enter")
+	if m.state != bookmarksView {
+		t.Fatalf("state = %v after saving to Recipes, err %v", m.state, m.addBookmark.err)
+	}
+	if n := len(m.list.Items()); n != len(unreadIDs)+1 {
+		t.Errorf("listed %d bookmarks after saving to Recipes, want %d", n, len(unreadIDs)+1)
+	}
+
+	readClipboard = func() (string, error) { return "", errors.New("no clipboard") }
+	m = press(t, m, "a", "not a url", "enter")
+	if m.state != addBookmarkView || m.addBookmark.err == nil {
+		t.Errorf("state = %v, err %v after an invalid URL, want an error in the dialog", m.state, m.addBookmark.err)
+	}
+	m = press(t, m, "esc")
+	if m.state != bookmarksView {
+		t.Errorf("state = %v after esc, want the bookmarks", m.state)
+	}
+
+	// a local file is sent as a private bookmark titled by the file name
+	path := filepath.Join(t.TempDir(), "todo.txt")
+	if err := os.WriteFile(path, []byte("milk\neggs & flour"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m = press(t, m, "a", path, "enter")
+	if m.state != bookmarksView {
+		t.Fatalf("state = %v after sending a file, err %v", m.state, m.addBookmark.err)
+	}
+	first, _ = m.list.Items()[0].(item)
+	if b, _, _ := srv.Bookmark(first.ID()); b.Title != "todo" || b.PrivateSource != privateSource {
+		t.Errorf("sent bookmark = %q from %q, want a private bookmark titled todo", b.Title, b.PrivateSource)
+	}
+}
+
+func TestModelBookmarkActions(t *testing.T) {
+	srv, _ := testEnv(t)
+	if err := tokenstore.Save(srv.IssueToken()); err != nil {
+		t.Fatal(err)
+	}
+	m := start(t, cache.New(t.TempDir()))
+
+	// the list starts with BookmarkSpec
+	m = press(t, m, "s")
+	if b, _, _ := srv.Bookmark(fakeserver.BookmarkSpec); b.Starred != "1" {
+		t.Error("s did not star the bookmark")
+	}
+	if i := m.list.Items()[0].(item); !i.Starred() || !strings.HasPrefix(i.Title(), "★") {
+		t.Errorf("item = %+v after starring, want it starred", i)
+	}
+
+	m = press(t, m, "e")
+	if _, folder, _ := srv.Bookmark(fakeserver.BookmarkSpec); folder != instapaper.FolderArchive {
+		t.Errorf("archived bookmark is in folder %q", folder)
+	}
+	if got, want := listedIDs(m), unreadIDs[1:]; !slices.Equal(got, want) {
+		t.Errorf("listed bookmarks after archiving = %v, want %v", got, want)
+	}
+
+	m = press(t, m, "x", "n")
+	if _, _, ok := srv.Bookmark(fakeserver.BookmarkBubbleTea); !ok {
+		t.Fatal("the bookmark was deleted without confirmation")
+	}
+	m = press(t, m, "x", "y")
+	if _, _, ok := srv.Bookmark(fakeserver.BookmarkBubbleTea); ok {
+		t.Error("the bookmark was not deleted after confirming")
+	}
+
+	// Home, Starred, Archive, Tech
+	m = press(t, m, "m", "down", "down", "down", "enter")
+	if _, folder, _ := srv.Bookmark(fakeserver.BookmarkOAuth); folder != strconv.FormatInt(fakeserver.FolderTech, 10) {
+		t.Errorf("moved bookmark is in folder %q, want %d", folder, fakeserver.FolderTech)
+	}
+	if m.state != bookmarksView || len(m.list.Items()) != 0 {
+		t.Errorf("state = %v with %d items after moving, want the empty list", m.state, len(m.list.Items()))
+	}
+}
+
+func TestModelBookmarkActionRollback(t *testing.T) {
+	srv, _ := testEnv(t)
+	if err := tokenstore.Save(srv.IssueToken()); err != nil {
+		t.Fatal(err)
+	}
+	m := start(t, cache.New(t.TempDir()))
+	m = press(t, m, "down")
+
+	srv.FailNext(1, fakeserver.Failure{Status: http.StatusBadRequest, Code: 1241})
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")})
+	m = next.(model)
+	if got, want := listedIDs(m), []int64{fakeserver.BookmarkSpec, fakeserver.BookmarkOAuth}; !slices.Equal(got, want) {
+		t.Errorf("listed bookmarks while archiving = %v, want %v", got, want)
+	}
+	m = drive(t, m, cmd)
+	if got := listedIDs(m); !slices.Equal(got, unreadIDs) {
+		t.Errorf("listed bookmarks after a failed archive = %v, want %v", got, unreadIDs)
+	}
+	if view := m.View(); !strings.Contains(view, "failed to archive") || !strings.Contains(view, "ctrl+r retry") {
+		t.Error("the view does not show the error with a retry action")
+	}
+
+	// BookmarkBubbleTea is starred, the failed unstar is shown on top of the failed archive
+	srv.FailNext(1, fakeserver.Failure{Status: http.StatusBadRequest, Code: 1241})
+	m = press(t, m, "s")
+	if i := m.list.Items()[1].(item); !i.Starred() {
+		t.Error("the bookmark is not starred after a failed unstar")
+	}
+	if len(m.notifications) != 2 || !strings.Contains(m.View(), "failed to unstar") {
+		t.Errorf("%d notifications, want the failed unstar and the failed archive", len(m.notifications))
+	}
+	m = press(t, m, "ctrl+r")
+	if i := m.list.Items()[1].(item); i.Starred() || len(m.notifications) != 1 {
+		t.Errorf("starred %v with %d notifications after retrying, want the bookmark unstarred", i.Starred(), len(m.notifications))
+	}
+	// the failed archive can still be retried
+	if view := m.View(); !strings.Contains(view, "failed to archive") || !strings.Contains(view, "ctrl+r retry") {
+		t.Error("the failed archive is not shown after retrying the unstar")
+	}
+	m = press(t, m, "ctrl+x")
+
+	srv.FailNext(1, fakeserver.Failure{Status: http.StatusBadRequest, Code: 1241})
+	m = press(t, m, "e", "ctrl+x")
+	if len(m.notifications) != 0 || strings.Contains(m.View(), "failed to archive") {
+		t.Error("the error is still shown after dismissing it")
+	}
+
+	// the item is not put back into the list of another folder
+	srv.FailNext(1, fakeserver.Failure{Status: http.StatusBadRequest, Code: 1241})
+	next, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")})
+	m = next.(model)
+	failed := runCmd(cmd)
+	if _, ok := failed.(actionErrMsg); !ok {
+		t.Fatalf("archiving sent %T, want an actionErrMsg", failed)
+	}
+	// Home, Starred, Archive
+	m = press(t, m, "tab", "tab", "down", "down", "enter")
+	archived := listedIDs(m)
+	m = drive(t, m, func() tea.Msg { return failed })
+	if got := listedIDs(m); !slices.Equal(got, archived) {
+		t.Errorf("listed bookmarks in Archive after a failed archive in Home = %v, want %v", got, archived)
+	}
+}
+
+func TestModelTagFilter(t *testing.T) {
+	srv, _ := testEnv(t)
+	if err := tokenstore.Save(srv.IssueToken()); err != nil {
+		t.Fatal(err)
+	}
+	m := start(t, cache.New(t.TempDir()))
+
+	// the most used tag comes first
+	m = press(t, m, "tab", "enter")
+	if got, want := listedIDs(m), []int64{fakeserver.BookmarkSpec, fakeserver.BookmarkBubbleTea}; !slices.Equal(got, want) {
+		t.Errorf("listed bookmarks tagged go = %v, want %v", got, want)
+	}
+	if row := m.table.Rows()[0]; row[tagMarkColumn] != "✓" || row[tagNameColumn] != "go" {
+		t.Errorf("first tag row = %v, want go selected", row)
+	}
+
+	m = press(t, m, "down", "enter")
+	if got, want := listedIDs(m), []int64{fakeserver.BookmarkBubbleTea}; !slices.Equal(got, want) {
+		t.Errorf("listed bookmarks tagged go and tui = %v, want %v", got, want)
+	}
+	m = press(t, m, "o")
+	if got, want := listedIDs(m), []int64{fakeserver.BookmarkSpec, fakeserver.BookmarkBubbleTea}; !slices.Equal(got, want) {
+		t.Errorf("listed bookmarks tagged go or tui = %v, want %v", got, want)
+	}
+	if !strings.Contains(m.View(), "tagged go or tui") {
+		t.Error("the view does not show the tag filter")
+	}
+
+	// the filter is cleared from the list too
+	m = press(t, m, "tab", "tab", "c")
+	if got := listedIDs(m); !slices.Equal(got, unreadIDs) {
+		t.Errorf("listed bookmarks after clearing the filter = %v, want %v", got, unreadIDs)
+	}
+}
+
+func TestModelTagEditor(t *testing.T) {
+	srv, _ := testEnv(t)
+	if err := tokenstore.Save(srv.IssueToken()); err != nil {
+		t.Fatal(err)
+	}
+	// a private bookmark in the archive keeps its tags when renaming
+	ctx := context.Background()
+	client, err := newCommandClient(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	private, err := client.AddBookmark(ctx, instapaper.AddBookmarkParams{Title: "Notes", Content: "<p>private</p>", PrivateSource: "gopaper", Tags: []string{"go"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ArchiveBookmark(ctx, private.BookmarkID); err != nil {
+		t.Fatal(err)
+	}
+	m := start(t, cache.New(t.TempDir()))
+	m.tagEditor.input.Cursor.SetMode(cursor.CursorStatic)
+
+	// the list starts with BookmarkSpec, tagged go
+	m = press(t, m, "t")
+	if m.state != tagEditorView || m.tagEditor.input.Value() != "go, " {
+		t.Fatalf("state = %v with tags %q after t, want the tag editor", m.state, m.tagEditor.input.Value())
+	}
+	m = press(t, m, "t", "tab", "enter")
+	if m.state != bookmarksView {
+		t.Fatalf("state = %v after saving the tags, err %v", m.state, m.tagEditor.err)
+	}
+	b, _, _ := srv.Bookmark(fakeserver.BookmarkSpec)
+	if got := instapaper.TagNames(b.Tags); !slices.Equal(got, []string{"go", "tui"}) {
+		t.Errorf("saved tags = %v, want the completed [go tui]", got)
+	}
+	if got := instapaper.TagNames(m.list.Items()[0].(item).Tags()); !slices.Equal(got, []string{"go", "tui"}) {
+		t.Errorf("listed tags = %v, want [go tui]", got)
+	}
+
+	m = press(t, m, "t", "esc")
+	if m.state != bookmarksView {
+		t.Errorf("state = %v after esc, want the bookmarks", m.state)
+	}
+
+	// a failed save is shown after the editor was closed
+	srv.FailNext(1, fakeserver.Failure{Status: http.StatusBadRequest, Code: 1250})
+	m = press(t, m, "t")
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = press(t, next.(model), "esc")
+	m = drive(t, m, cmd)
+	if m.state != bookmarksView || !strings.Contains(m.View(), "failed to save tags") {
+		t.Errorf("state = %v after a failed save in the closed editor, want the bookmarks with the error", m.state)
+	}
+	m = press(t, m, "ctrl+x")
+
+	// go and tui are used twice, go comes first
+	m = press(t, m, "tab", "r", "golang", "enter")
+	if m.state != tagsView {
+		t.Fatalf("state = %v after renaming, err %v", m.state, m.tagEditor.err)
+	}
+	if b, _, _ := srv.Bookmark(fakeserver.BookmarkNoText); !slices.Equal(instapaper.TagNames(b.Tags), []string{"golang"}) {
+		t.Errorf("tags in another folder = %v, want the tag renamed", instapaper.TagNames(b.Tags))
+	}
+	if names := m.tagNames(); !slices.Equal(names, []string{"golang", "tui"}) {
+		t.Errorf("tags = %v after renaming, want [golang tui]", names)
+	}
+	if view := m.View(); !strings.Contains(view, "1 private bookmark(s) still tagged") {
+		t.Error("the view does not say the private bookmark was skipped")
+	}
+
+	// Home, Starred, Archive
+	m = press(t, m, "ctrl+x", "tab", "down", "down", "enter", "tab", "t")
+	if m.state != bookmarksView || !strings.Contains(m.View(), "cannot edit the tags") {
+		t.Errorf("state = %v after t on a private bookmark, want the bookmarks with an error", m.state)
+	}
+}
+
+func TestModelLoadsMorePages(t *testing.T) {
+	srv, _ := testEnv(t)
+	if err := tokenstore.Save(srv.IssueToken()); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	client, err := newCommandClient(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range bookmarkLimit + 10 {
+		if _, err := client.AddBookmark(ctx, instapaper.AddBookmarkParams{URL: "https://example.com/page/" + strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	total := bookmarkLimit + 10 + len(unreadIDs)
+
+	m := start(t, cache.New(t.TempDir()))
+	if n := len(m.list.Items()); n != bookmarkLimit {
+		t.Fatalf("listed %d bookmarks, want the first page of %d", n, bookmarkLimit)
+	}
+	if !strings.Contains(m.View(), fmt.Sprintf("%d+ bookmarks", bookmarkLimit)) {
+		t.Error("the view does not show that there are more bookmarks")
+	}
+	m = press(t, m, "G")
+	if n := len(m.list.Items()); n != total {
+		t.Errorf("listed %d bookmarks at the end of the list, want all %d", n, total)
+	}
+	if !strings.Contains(m.View(), fmt.Sprintf("%d bookmarks", total)) {
+		t.Error("the view does not show the total")
+	}
+}
+
+func TestModelStatusBar(t *testing.T) {
+	srv, _ := testEnv(t)
+	if err := tokenstore.Save(srv.IssueToken()); err != nil {
+		t.Fatal(err)
+	}
+	c := cache.New(t.TempDir())
+	m := start(t, c)
+	status := m.statusBar(120)
+	for _, want := range []string{fakeserver.Username + " (premium)", "Home", "3 bookmarks", "synced"} {
+		if !strings.Contains(status, want) {
+			t.Errorf("status bar %q does not show %q", status, want)
+		}
+	}
+	if user, err := c.User(); err != nil || user.Username != fakeserver.Username {
+		t.Errorf("cached user = %+v, %v, want %q", user, err, fakeserver.Username)
+	}
+	// the count follows the actions
+	m = press(t, m, "e")
+	if status := m.statusBar(120); !strings.Contains(status, "2 bookmarks") {
+		t.Errorf("status bar %q does not show the 2 bookmarks left after archiving", status)
+	}
+
+	// progress saved while offline
+	if err := c.QueueProgress(cache.PendingProgress{BookmarkID: fakeserver.BookmarkSpec, Progress: 0.5, Timestamp: 1}); err != nil {
+		t.Fatal(err)
+	}
+	m = drive(t, m, func() tea.Msg { return syncErrMsg{kind: syncNotification, err: errors.New("offline")} })
+	if status := m.statusBar(120); !strings.Contains(status, "1 update queued") {
+		t.Errorf("status bar %q does not show the queued update", status)
+	}
+}
@@ -0,0 +1,246 @@
+// importing email newsletters as private bookmarks
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+
+	"github.com/ieroNo47/gopaper/internal/instapaper"
+	"github.com/ieroNo47/gopaper/internal/ledger"
+	"github.com/microcosm-cc/bluemonday"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// newsletterSource is sent as is_private_from_source for imported newsletters
+const newsletterSource = "email"
+
+// wordDecoder decodes the encoded words of headers in any charset, not only UTF-8 and Latin-1
+var wordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+// newsletterPolicy removes scripts, styles, forms and event handlers, links and images are kept
+var newsletterPolicy = bluemonday.UGCPolicy()
+
+// newsletter is an email with the sanitized HTML to save
+type newsletter struct {
+	// messageID is the Message-ID header, or a hash of the message if it has none
+	messageID string
+	subject   string
+	// sender is the name or address of the sender, used as the tag
+	sender string
+	html   string
+}
+
+// readNewsletters parses an .eml file or an mbox archive of emails. Messages that cannot be
+// parsed are skipped, their errors name the position of the message in the archive.
+func readNewsletters(data []byte) (newsletters []newsletter, skipped []error) {
+	messages := [][]byte{data}
+	if bytes.HasPrefix(data, []byte("From ")) {
+		messages = splitMbox(data)
+	}
+	newsletters = []newsletter{}
+	for i, raw := range messages {
+		n, err := parseNewsletter(raw)
+		if err != nil {
+			skipped = append(skipped, fmt.Errorf("message %d: %w", i+1, err))
+			continue
+		}
+		newsletters = append(newsletters, n)
+	}
+	return newsletters, skipped
+}
+
+// bookmark returns the parameters to save the newsletter as a private bookmark
+func (n newsletter) bookmark(folderID int64) instapaper.AddBookmarkParams {
+	params := instapaper.AddBookmarkParams{
+		Title:         cmp.Or(n.subject, "(no subject)"),
+		FolderID:      folderID,
+		Content:       n.html,
+		PrivateSource: newsletterSource,
+	}
+	if n.sender != "" {
+		params.Tags = []string{n.sender}
+	}
+	return params
+}
+
+// importNewsletters saves the newsletters that were not imported before. It returns the new
+// bookmarks and the number of skipped duplicates. Every import is recorded right away,
+// so an import that failed partway can be run again.
+func importNewsletters(ctx context.Context, client instapaper.Client, l *ledger.Ledger, newsletters []newsletter, folderID int64) ([]instapaper.Bookmark, int, error) {
+	imported, err := l.Imported()
+	if err != nil {
+		return nil, 0, err
+	}
+	added := []instapaper.Bookmark{}
+	skipped := 0
+	for _, n := range newsletters {
+		key := "message:" + n.messageID
+		if _, ok := imported[key]; ok {
+			skipped++
+			continue
+		}
+		bookmark, err := client.AddBookmark(ctx, n.bookmark(folderID))
+		if err != nil {
+			return added, skipped, fmt.Errorf("failed to import %q: %w", n.subject, err)
+		}
+		if err := l.Save(key, bookmark.BookmarkID); err != nil {
+			return added, skipped, err
+		}
+		imported[key] = bookmark.BookmarkID
+		added = append(added, bookmark)
+	}
+	return added, skipped, nil
+}
+
+// misc helper functions
+
+// splitMbox splits an mbox archive at the "From " lines that start every message
+// and unquotes the ">From " lines of the bodies
+func splitMbox(data []byte) [][]byte {
+	var messages [][]byte
+	var current []byte
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("From ")) {
+			if current != nil {
+				messages = append(messages, current)
+			}
+			current = []byte{}
+			continue
+		}
+		if unquoted, ok := bytes.CutPrefix(line, []byte(">")); ok && bytes.HasPrefix(bytes.TrimLeft(unquoted, ">"), []byte("From ")) {
+			line = unquoted
+		}
+		current = append(current, line...)
+	}
+	if current != nil {
+		messages = append(messages, current)
+	}
+	return messages
+}
+
+func parseNewsletter(raw []byte) (newsletter, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return newsletter{}, err
+	}
+	subject := msg.Header.Get("Subject")
+	if decoded, err := wordDecoder.DecodeHeader(subject); err == nil {
+		subject = decoded
+	}
+	n := newsletter{
+		messageID: strings.Trim(strings.TrimSpace(msg.Header.Get("Message-ID")), "<>"),
+		subject:   strings.TrimSpace(subject),
+		sender:    sender(msg.Header),
+	}
+	if n.messageID == "" {
+		sum := sha256.Sum256(raw)
+		n.messageID = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	html, text, err := messageBody(textproto.MIMEHeader(msg.Header), msg.Body)
+	switch {
+	case err != nil:
+		return newsletter{}, err
+	case html != "":
+		n.html = newsletterPolicy.Sanitize(html)
+	case text != "":
+		n.html = textToHTML(text)
+	default:
+		return newsletter{}, errors.New("no HTML or text part")
+	}
+	return n, nil
+}
+
+// sender is the name of the first sender, or the address if it has no name.
+// Commas are removed since they separate tags.
+func sender(header mail.Header) string {
+	parser := mail.AddressParser{WordDecoder: wordDecoder}
+	addresses, err := parser.ParseList(header.Get("From"))
+	if err != nil || len(addresses) == 0 {
+		return ""
+	}
+	name := cmp.Or(addresses[0].Name, addresses[0].Address)
+	return strings.Join(strings.Fields(strings.ReplaceAll(name, ",", " ")), " ")
+}
+
+// messageBody returns the first HTML and the first plain text part of a message or a MIME part.
+// Attachments are skipped.
+func messageBody(header textproto.MIMEHeader, body io.Reader) (html, text string, err error) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		// messages without a valid content type are plain text
+		mediaType, params = "text/plain", nil
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		r := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := r.NextPart()
+			if errors.Is(err, io.EOF) {
+				return html, text, nil
+			}
+			if err != nil {
+				return "", "", fmt.Errorf("failed to read MIME part: %w", err)
+			}
+			if disposition, _, _ := mime.ParseMediaType(part.Header.Get("Content-Disposition")); disposition == "attachment" {
+				continue
+			}
+			partHTML, partText, err := messageBody(part.Header, part)
+			if err != nil {
+				return "", "", err
+			}
+			html = cmp.Or(html, partHTML)
+			text = cmp.Or(text, partText)
+		}
+	}
+	if mediaType != "text/html" && mediaType != "text/plain" {
+		return "", "", nil
+	}
+	// multipart readers decode quoted-printable parts themselves and remove the header
+	switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to decode %s part: %w", mediaType, err)
+	}
+	content := decodeCharset(params["charset"], data)
+	if mediaType == "text/html" {
+		return content, "", nil
+	}
+	return "", content, nil
+}
+
+// decodeCharset converts text in a charset known to browsers to UTF-8. Text without a charset
+// or with an unknown one is taken as UTF-8, invalid bytes are replaced.
+func decodeCharset(charset string, data []byte) string {
+	if enc, err := htmlindex.Get(cmp.Or(charset, "utf-8")); err == nil {
+		if decoded, err := enc.NewDecoder().Bytes(data); err == nil {
+			return string(decoded)
+		}
+	}
+	return strings.ToValidUTF8(string(data), "\uFFFD")
+}
+
+// charsetReader converts the encoded words of headers to UTF-8, see wordDecoder
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, err
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
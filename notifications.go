@@ -0,0 +1,102 @@
+// error notifications with a retry action
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+var notificationStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("15")).
+	Background(lipgloss.Color("1")).
+	Padding(0, 1)
+
+type notificationKeyMap struct {
+	retry   key.Binding
+	dismiss key.Binding
+}
+
+// the keys work in every view, text inputs don't use them
+var notificationKeys = notificationKeyMap{
+	retry: key.NewBinding(
+		key.WithKeys("ctrl+r"),
+		key.WithHelp("ctrl+r", "retry"),
+	),
+	dismiss: key.NewBinding(
+		key.WithKeys("ctrl+x"),
+		key.WithHelp("ctrl+x", "dismiss"),
+	),
+}
+
+// retryFunc runs a failed operation again
+type retryFunc func(m *model) tea.Cmd
+
+// notification is an error shown in place of the status bar until it is dismissed or retried.
+// A new notification with the same kind replaces the previous one, e.g. for repeated sync errors.
+type notification struct {
+	kind  string
+	err   error
+	retry retryFunc
+}
+
+// kinds of notifications
+const (
+	syncNotification    = "sync"
+	foldersNotification = "folders"
+	actionNotification  = "action"
+	tagsNotification    = "tags"
+)
+
+// actionKind is the kind of the notification of a failed action, every failed action
+// on a bookmark can be retried
+func actionKind(action bookmarkAction, bookmarkID int64) string {
+	return fmt.Sprintf("%s %v %d", actionNotification, action, bookmarkID)
+}
+
+// notify shows an error, the newest notification is shown first
+func (m *model) notify(kind string, err error, retry retryFunc) {
+	m.dismiss(kind)
+	m.notifications = append(m.notifications, notification{kind: kind, err: err, retry: retry})
+}
+
+// dismiss removes the notification of a kind, e.g. after the operation succeeded
+func (m *model) dismiss(kind string) {
+	m.notifications = slices.DeleteFunc(m.notifications, func(n notification) bool { return n.kind == kind })
+}
+
+// updateNotifications handles the keys of the newest notification. It reports whether the key was used.
+func (m *model) updateNotifications(msg tea.KeyMsg) (tea.Cmd, bool) {
+	if len(m.notifications) == 0 {
+		return nil, false
+	}
+	n := m.notifications[len(m.notifications)-1]
+	switch {
+	case key.Matches(msg, notificationKeys.retry) && n.retry != nil:
+		m.dismiss(n.kind)
+		return n.retry(m), true
+	case key.Matches(msg, notificationKeys.dismiss):
+		m.dismiss(n.kind)
+		return nil, true
+	}
+	return nil, false
+}
+
+// notificationBar shows the newest notification and how to act on it
+func (m model) notificationBar(width int) string {
+	n := m.notifications[len(m.notifications)-1]
+	actions := notificationKeys.dismiss.Help().Key + " " + notificationKeys.dismiss.Help().Desc
+	if n.retry != nil {
+		actions = notificationKeys.retry.Help().Key + " " + notificationKeys.retry.Help().Desc + " • " + actions
+	}
+	if more := len(m.notifications) - 1; more > 0 {
+		actions += fmt.Sprintf(" • %d more", more)
+	}
+	text := ansi.Truncate(n.err.Error(), max(width-notificationStyle.GetHorizontalPadding()-lipgloss.Width(actions)-3, 0), "…")
+	text += " • " + actions
+	return notificationStyle.Width(width).Render(text)
+}
@@ -0,0 +1,396 @@
+// article reader view
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	htmltomarkdown "github.com/JohannesKaufmann/html-to-markdown/v2"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/ieroNo47/gopaper/internal/cache"
+	"github.com/ieroNo47/gopaper/internal/instapaper"
+)
+
+// progressDebounce is how long the reader waits after scrolling before saving the read progress
+const progressDebounce = 2 * time.Second
+
+var readerStyle = lipgloss.NewStyle().
+	Margin(0).
+	Padding(0).
+	BorderStyle(lipgloss.RoundedBorder()).
+	BorderForeground(lipgloss.Color("5")).
+	MarginBackground(lipgloss.Color("5"))
+
+var readerTitleStyle = lipgloss.NewStyle().Bold(true).Padding(0, 1)
+
+var readerFooterStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Padding(0, 1)
+
+type readerKeyMap struct {
+	top       key.Binding
+	bottom    key.Binding
+	highlight key.Binding
+	retry     key.Binding
+	back      key.Binding
+}
+
+var readerKeys = readerKeyMap{
+	top: key.NewBinding(
+		key.WithKeys("g", "home"),
+		key.WithHelp("g", "top"),
+	),
+	bottom: key.NewBinding(
+		key.WithKeys("G", "end"),
+		key.WithHelp("G", "bottom"),
+	),
+	highlight: key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "highlight top line"),
+	),
+	retry: key.NewBinding(
+		key.WithKeys("r"),
+		key.WithHelp("r", "retry"),
+	),
+	back: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "back"),
+	),
+}
+
+// articleMsg has the article of a bookmark converted to markdown and rendered for the terminal
+type articleMsg struct {
+	bookmarkID int64
+	markdown   string
+	rendered   string
+}
+
+type articleErrMsg struct {
+	bookmarkID int64
+	err        error
+}
+
+// loadArticle gets the article text from the cache or the API and renders it
+func loadArticle(ctx context.Context, client instapaper.Client, c *cache.Cache, bookmark instapaper.Bookmark, width int) tea.Cmd {
+	return func() tea.Msg {
+		text, err := c.Text(bookmark)
+		if err != nil {
+			text, err = client.GetBookmarkText(ctx, bookmark.BookmarkID)
+			if ctx.Err() != nil {
+				// the reader was closed
+				return nil
+			}
+			if err != nil {
+				return articleErrMsg{bookmark.BookmarkID, fmt.Errorf("failed to get article: %w", err)}
+			}
+			_ = c.SaveText(bookmark, text)
+		}
+		markdown, err := htmltomarkdown.ConvertString(text)
+		if err != nil {
+			return articleErrMsg{bookmark.BookmarkID, fmt.Errorf("failed to convert article to markdown: %w", err)}
+		}
+		return renderArticle(bookmark.BookmarkID, markdown, width)()
+	}
+}
+
+// renderArticle renders markdown wrapped to the width of the reader
+func renderArticle(bookmarkID int64, markdown string, width int) tea.Cmd {
+	return func() tea.Msg {
+		renderer, err := glamour.NewTermRenderer(
+			glamour.WithStandardStyle("dark"),
+			glamour.WithWordWrap(width),
+		)
+		if err != nil {
+			return articleErrMsg{bookmarkID, fmt.Errorf("failed to create renderer: %w", err)}
+		}
+		rendered, err := renderer.Render(markdown)
+		if err != nil {
+			return articleErrMsg{bookmarkID, fmt.Errorf("failed to render article: %w", err)}
+		}
+		return articleMsg{bookmarkID: bookmarkID, markdown: markdown, rendered: rendered}
+	}
+}
+
+// progressTickMsg fires after scrolling, the progress is only saved if seq is still the latest
+type progressTickMsg struct {
+	bookmarkID int64
+	seq        int
+}
+
+// progressSavedMsg has the bookmark with the new progress. If the API couldn't be
+// reached the update is queued and the bookmark only has the progress set locally.
+type progressSavedMsg struct {
+	bookmark instapaper.Bookmark
+	queued   bool
+	err      error
+}
+
+// saveProgress sends the read progress of a bookmark, queueing it in the cache when offline
+func saveProgress(ctx context.Context, client instapaper.Client, c *cache.Cache, bookmark instapaper.Bookmark, progress float64) tea.Cmd {
+	timestamp := time.Now()
+	return func() tea.Msg {
+		updated, err := client.UpdateReadProgress(ctx, bookmark.BookmarkID, progress, timestamp)
+		if err == nil {
+			return progressSavedMsg{bookmark: updated}
+		}
+		bookmark.Progress = progress
+		bookmark.ProgressTimestamp = timestamp.Unix()
+		var apiErr *instapaper.APIError
+		if errors.As(err, &apiErr) && !apiErr.Temporary() {
+			// the API rejected the update, sending it again won't help
+			return progressSavedMsg{bookmark: bookmark, err: err}
+		}
+		err = c.QueueProgress(cache.PendingProgress{
+			BookmarkID: bookmark.BookmarkID,
+			Progress:   progress,
+			Timestamp:  timestamp.Unix(),
+		})
+		return progressSavedMsg{bookmark: bookmark, queued: true, err: err}
+	}
+}
+
+// sendPendingProgress sends the read progress queued while offline.
+// It stops at the first failure, the rest is sent on the next call.
+func sendPendingProgress(ctx context.Context, client instapaper.Client, c *cache.Cache) error {
+	pending, err := c.PendingProgress()
+	if err != nil {
+		return err
+	}
+	for _, p := range pending {
+		_, err := client.UpdateReadProgress(ctx, p.BookmarkID, p.Progress, time.Unix(p.Timestamp, 0))
+		var apiErr *instapaper.APIError
+		if err != nil && !(errors.As(err, &apiErr) && !apiErr.Temporary()) {
+			return err
+		}
+		// updates rejected by the API are dropped as well
+		if err := c.RemovePendingProgress(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readerModel shows the article of a bookmark
+type readerModel struct {
+	// ctx is the context the reader was opened with, cancel stops loading the article
+	ctx      context.Context
+	cancel   context.CancelFunc
+	client   instapaper.Client
+	cache    *cache.Cache
+	bookmark instapaper.Bookmark
+	viewport viewport.Model
+	spinner  spinner.Model
+	markdown string
+	loading  bool
+	err      error
+	width    int
+	height   int
+	// progress is the scroll position as a value between 0 and 1, savedProgress the last one sent
+	progress      float64
+	savedProgress float64
+	progressSeq   int
+	// progressStatus tells the user if the progress couldn't be sent
+	progressStatus string
+}
+
+func newReaderModel() readerModel {
+	return readerModel{
+		viewport: viewport.New(0, 0),
+		spinner:  spinner.New(spinner.WithSpinner(spinner.Dot)),
+	}
+}
+
+// open resets the reader and starts loading the article of a bookmark
+func (m readerModel) open(ctx context.Context, client instapaper.Client, c *cache.Cache, bookmark instapaper.Bookmark) (readerModel, tea.Cmd) {
+	m.close()
+	m.ctx = ctx
+	loadCtx, cancel := context.WithCancel(ctx)
+	m.cancel = cancel
+	m.client = client
+	m.cache = c
+	m.bookmark = bookmark
+	m.markdown = ""
+	m.err = nil
+	m.loading = true
+	m.progress = bookmark.Progress
+	m.savedProgress = bookmark.Progress
+	m.progressStatus = ""
+	m.viewport.SetContent("")
+	m.viewport.GotoTop()
+	return m, tea.Batch(m.spinner.Tick, loadArticle(loadCtx, client, c, bookmark, m.viewport.Width))
+}
+
+// close stops loading the article if it is still in flight
+func (m readerModel) close() {
+	if m.cancel != nil {
+		m.cancel()
+	}
+}
+
+func (m *readerModel) setSize(width, height int) tea.Cmd {
+	m.width = width
+	m.height = height
+	// leave room for the title and footer lines
+	m.viewport.Width = width
+	m.viewport.Height = max(height-2, 0)
+	if m.markdown == "" {
+		return nil
+	}
+	return renderArticle(m.bookmark.BookmarkID, m.markdown, width)
+}
+
+// saveProgress returns a command to save the progress if it changed since it was last saved
+func (m *readerModel) saveProgress() tea.Cmd {
+	if m.markdown == "" || m.progress == m.savedProgress {
+		return nil
+	}
+	m.savedProgress = m.progress
+	// the progress is saved even if the reader is closed or the app quits in the meantime
+	return saveProgress(context.WithoutCancel(m.ctx), m.client, m.cache, m.bookmark, m.progress)
+}
+
+// scrolled updates the progress after the viewport moved and schedules saving it
+func (m *readerModel) scrolled() tea.Cmd {
+	if m.markdown == "" {
+		return nil
+	}
+	progress := math.Round(m.viewport.ScrollPercent()*100) / 100
+	if progress == m.progress {
+		return nil
+	}
+	m.progress = progress
+	m.progressSeq++
+	msg := progressTickMsg{bookmarkID: m.bookmark.BookmarkID, seq: m.progressSeq}
+	return tea.Tick(progressDebounce, func(time.Time) tea.Msg { return msg })
+}
+
+// scrollTo moves the viewport to a progress value between 0 and 1
+func (m *readerModel) scrollTo(progress float64) {
+	maxOffset := m.viewport.TotalLineCount() - m.viewport.Height
+	if maxOffset <= 0 {
+		return
+	}
+	m.viewport.SetYOffset(int(math.Round(progress * float64(maxOffset))))
+}
+
+// topLine returns the first line of text visible in the viewport
+func (m readerModel) topLine() string {
+	lines := strings.Split(m.viewport.View(), "\n")
+	for _, line := range lines {
+		if text := strings.TrimSpace(ansi.Strip(line)); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+func (m readerModel) Update(msg tea.Msg) (readerModel, tea.Cmd) {
+	var cmd tea.Cmd
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, readerKeys.retry) && m.err != nil:
+			return m.open(m.ctx, m.client, m.cache, m.bookmark)
+		case key.Matches(msg, readerKeys.top):
+			m.viewport.GotoTop()
+		case key.Matches(msg, readerKeys.bottom):
+			m.viewport.GotoBottom()
+		default:
+			m.viewport, cmd = m.viewport.Update(msg)
+		}
+		return m, tea.Batch(cmd, m.scrolled())
+	case progressTickMsg:
+		if msg.bookmarkID != m.bookmark.BookmarkID || msg.seq != m.progressSeq {
+			// scrolled again since
+			return m, nil
+		}
+		return m, m.saveProgress()
+	case progressSavedMsg:
+		if msg.bookmark.BookmarkID != m.bookmark.BookmarkID {
+			return m, nil
+		}
+		m.bookmark.Progress = msg.bookmark.Progress
+		m.bookmark.ProgressTimestamp = msg.bookmark.ProgressTimestamp
+		switch {
+		case msg.err != nil:
+			m.progressStatus = "progress not saved: " + msg.err.Error()
+		case msg.queued:
+			m.progressStatus = "offline, progress will be synced later"
+		default:
+			m.progressStatus = ""
+		}
+	case spinner.TickMsg:
+		if !m.loading {
+			return m, nil
+		}
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+	case articleMsg:
+		if msg.bookmarkID != m.bookmark.BookmarkID {
+			return m, nil
+		}
+		m.loading = false
+		// resume at the saved progress when opening, keep the position when re-rendering after a resize
+		progress := m.progress
+		if m.markdown != "" {
+			progress = m.viewport.ScrollPercent()
+		}
+		m.markdown = msg.markdown
+		m.viewport.SetContent(msg.rendered)
+		m.scrollTo(progress)
+	case articleErrMsg:
+		if msg.bookmarkID != m.bookmark.BookmarkID {
+			return m, nil
+		}
+		m.loading = false
+		m.err = msg.err
+	}
+	return m, nil
+}
+
+func (m readerModel) View() string {
+	title := readerTitleStyle.Render(ansi.Truncate(m.bookmark.Title, max(m.width-2, 0), "…"))
+	var body, footer string
+	switch {
+	case m.err != nil:
+		msg := m.err.Error()
+		if errors.Is(m.err, instapaper.ErrTextUnavailable) {
+			msg = "Instapaper has no text version of this article, open " + m.bookmark.URL + " in a browser"
+		}
+		body = lipgloss.Place(m.width, m.viewport.Height, lipgloss.Center, lipgloss.Center,
+			errStyle.Width(m.width/2).Render(msg))
+	case m.loading:
+		body = lipgloss.Place(m.width, m.viewport.Height, lipgloss.Center, lipgloss.Center,
+			m.spinner.View()+" Loading article...")
+	default:
+		body = m.viewport.View()
+		footer = fmt.Sprintf("%3.0f%%", m.viewport.ScrollPercent()*100)
+		if m.progressStatus != "" {
+			footer += " • " + m.progressStatus
+		}
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, title, body, readerFooterStyle.Render(footer))
+}
+
+func (m readerModel) ShortHelp() []key.Binding {
+	if m.err != nil {
+		return []key.Binding{readerKeys.retry, readerKeys.back}
+	}
+	return []key.Binding{m.viewport.KeyMap.Down, m.viewport.KeyMap.Up, m.viewport.KeyMap.PageDown, readerKeys.highlight, readerKeys.back}
+}
+
+func (m readerModel) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{m.viewport.KeyMap.Down, m.viewport.KeyMap.Up, m.viewport.KeyMap.PageDown, m.viewport.KeyMap.PageUp},
+		{m.viewport.KeyMap.HalfPageDown, m.viewport.KeyMap.HalfPageUp, readerKeys.top, readerKeys.bottom},
+		{readerKeys.highlight, keys.highlights, readerKeys.retry, readerKeys.back},
+	}
+}
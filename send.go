@@ -0,0 +1,140 @@
+// sending local files as private bookmarks
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ieroNo47/gopaper/internal/instapaper"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// privateSource is sent as is_private_from_source for uploaded files
+const privateSource = "gopaper"
+
+// sendExtensions are the extensions of the files that can be sent
+var sendExtensions = []string{".md", ".markdown", ".html", ".htm", ".txt", ".text"}
+
+var htmlTitlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// fileBookmark reads a Markdown, HTML or text file and returns the parameters to save it
+// as a private bookmark. The title is the first heading or the HTML title, else the file name.
+// Other files, and files that are not UTF-8, are rejected.
+func fileBookmark(path string) (instapaper.AddBookmarkParams, error) {
+	name := filepath.Base(path)
+	ext := strings.ToLower(filepath.Ext(path))
+	if !slices.Contains(sendExtensions, ext) {
+		return instapaper.AddBookmarkParams{}, fmt.Errorf("%s is not a Markdown, HTML or text file, use %s", name, strings.Join(sendExtensions, ", "))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return instapaper.AddBookmarkParams{}, err
+	}
+	if !utf8.Valid(data) {
+		return instapaper.AddBookmarkParams{}, fmt.Errorf("%s is not UTF-8 text", name)
+	}
+	params := instapaper.AddBookmarkParams{
+		Title:         strings.TrimSuffix(name, filepath.Ext(name)),
+		PrivateSource: privateSource,
+	}
+	var title string
+	switch ext {
+	case ".md", ".markdown":
+		title = markdownTitle(data)
+		if params.Content, err = markdownToHTML(data); err != nil {
+			return instapaper.AddBookmarkParams{}, fmt.Errorf("failed to convert %s to HTML: %w", name, err)
+		}
+	case ".html", ".htm":
+		if m := htmlTitlePattern.FindSubmatch(data); m != nil {
+			title = html.UnescapeString(string(m[1]))
+		}
+		params.Content = string(data)
+	default:
+		params.Content = textToHTML(string(data))
+	}
+	if title = strings.TrimSpace(title); title != "" {
+		params.Title = title
+	}
+	if strings.TrimSpace(params.Content) == "" {
+		return instapaper.AddBookmarkParams{}, fmt.Errorf("%s is empty", name)
+	}
+	return params, nil
+}
+
+// localFile returns the path if s is an existing file rather than a web address. ~ is the home directory.
+func localFile(s string) (string, bool) {
+	if u, err := url.Parse(s); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		return "", false
+	}
+	if rest, ok := strings.CutPrefix(s, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", false
+		}
+		s = filepath.Join(home, rest)
+	}
+	info, err := os.Stat(s)
+	if err != nil || info.IsDir() {
+		return "", false
+	}
+	return s, true
+}
+
+// misc helper functions
+
+func markdownToHTML(src []byte) (string, error) {
+	var buf bytes.Buffer
+	md := goldmark.New(goldmark.WithExtensions(extension.GFM))
+	if err := md.Convert(src, &buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// markdownTitle returns the text of the first level 1 heading, comments in fenced code blocks are skipped
+func markdownTitle(src []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(src))
+	// fence is the ``` or ~~~ that opened the code block the line is in
+	fence := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimLeft(line, " ")
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) && strings.TrimSpace(strings.TrimLeft(trimmed, fence[:1])) == "" {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```"), strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, trimmed[:1]))]
+		default:
+			if title, ok := strings.CutPrefix(line, "# "); ok {
+				return title
+			}
+		}
+	}
+	return ""
+}
+
+// textToHTML makes a paragraph of each block of lines
+func textToHTML(text string) string {
+	var b strings.Builder
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		if paragraph = strings.TrimSpace(paragraph); paragraph == "" {
+			continue
+		}
+		lines := strings.Split(html.EscapeString(paragraph), "\n")
+		b.WriteString("<p>" + strings.Join(lines, "<br>\n") + "</p>\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,107 @@
+// status bar with the account and sync state
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/ieroNo47/gopaper/internal/cache"
+	"github.com/ieroNo47/gopaper/internal/instapaper"
+)
+
+var statusBarStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("0")).
+	Background(lipgloss.Color("4")).
+	Padding(0, 1)
+
+// userMsg has the account of the access token
+type userMsg struct {
+	user instapaper.User
+}
+
+// verifyCredentials gets the account for the status bar. Failures are ignored,
+// the cached account is shown and a revoked token is noticed by the sync.
+func verifyCredentials(ctx context.Context, client instapaper.Client, c *cache.Cache) tea.Cmd {
+	return func() tea.Msg {
+		user, err := client.VerifyCredentials(ctx)
+		if err != nil {
+			return nil
+		}
+		_ = c.SaveUser(user)
+		return userMsg{user: user}
+	}
+}
+
+// updatePendingCount counts the read progress updates waiting to be sent
+func (m *model) updatePendingCount() {
+	pending, _ := m.cache.PendingProgress()
+	m.pendingCount = len(pending)
+}
+
+// updateBookmarkCount counts the synced bookmarks of the listed folder, the status bar
+// does not go through the library on every render
+func (m *model) updateBookmarkCount() {
+	library := m.library()
+	m.bookmarkCount = library.Len()
+	m.moreBookmarks = !library.Complete()
+}
+
+// statusBar shows who is logged in, what is listed and whether everything is synced
+func (m model) statusBar(width int) string {
+	if m.state == loginView {
+		return statusBarStyle.Width(width).Render("not logged in")
+	}
+	parts := []string{}
+	if m.user.Username != "" {
+		subscription := "no subscription"
+		if m.user.SubscriptionIsActive == "1" {
+			subscription = "premium"
+		}
+		parts = append(parts, fmt.Sprintf("%s (%s)", m.user.Username, subscription))
+	}
+	parts = append(parts, m.folderTitle(), m.countStatus())
+	switch {
+	case m.syncing:
+		parts = append(parts, "syncing…")
+	case m.lastSync.IsZero():
+		parts = append(parts, "not synced")
+	default:
+		parts = append(parts, "synced "+m.lastSync.Format(time.Kitchen))
+	}
+	switch {
+	case m.pendingCount == 1:
+		parts = append(parts, "1 update queued")
+	case m.pendingCount > 1:
+		parts = append(parts, strconv.Itoa(m.pendingCount)+" updates queued")
+	}
+	text := ansi.Truncate(strings.Join(parts, " • "), width-statusBarStyle.GetHorizontalPadding(), "…")
+	return statusBarStyle.Width(width).Render(text)
+}
+
+// countStatus is the number of listed bookmarks, with a + while there are more to load
+func (m model) countStatus() string {
+	total := strconv.Itoa(m.bookmarkCount)
+	if m.moreBookmarks {
+		total += "+"
+	}
+	if m.tagFilter.active() {
+		return fmt.Sprintf("%d of %s bookmarks", len(m.list.Items()), total)
+	}
+	return total + " bookmarks"
+}
+
+// folderTitle is the title of the listed folder
+func (m model) folderTitle() string {
+	for _, f := range m.folders {
+		if f.id == m.folderID {
+			return f.title
+		}
+	}
+	return m.folderID
+}
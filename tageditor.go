@@ -0,0 +1,241 @@
+// tag editor dialog
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ieroNo47/gopaper/internal/instapaper"
+)
+
+type tagEditorKeyMap struct {
+	complete key.Binding
+	submit   key.Binding
+	cancel   key.Binding
+}
+
+var tagEditorKeys = tagEditorKeyMap{
+	complete: key.NewBinding(
+		key.WithKeys("tab"),
+		key.WithHelp("tab", "complete"),
+	),
+	submit: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("enter", "save"),
+	),
+	cancel: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "cancel"),
+	),
+}
+
+// tagsSavedMsg has the bookmarks with new tags. from and to are set after renaming a tag,
+// skipped is the number of private bookmarks that still have the old tag.
+type tagsSavedMsg struct {
+	bookmarks []instapaper.Bookmark
+	from, to  string
+	skipped   int
+}
+
+// tagsErrMsg has the error and the bookmarks that were changed before it, renaming can fail partway
+type tagsErrMsg struct {
+	bookmarks []instapaper.Bookmark
+	err       error
+}
+
+func setTags(ctx context.Context, client instapaper.Client, bookmark instapaper.Bookmark, folderID string, tags []string) tea.Cmd {
+	return func() tea.Msg {
+		updated, err := client.SetBookmarkTags(ctx, bookmark.URL, folderID, tags)
+		if err != nil {
+			return tagsErrMsg{err: fmt.Errorf("failed to save tags: %w", err)}
+		}
+		return tagsSavedMsg{bookmarks: []instapaper.Bookmark{updated}}
+	}
+}
+
+func renameTag(ctx context.Context, client instapaper.Client, from, to string) tea.Cmd {
+	return func() tea.Msg {
+		renamed, skipped, err := client.RenameTag(ctx, from, to)
+		if err != nil {
+			return tagsErrMsg{bookmarks: renamed, err: fmt.Errorf("failed to rename tag: %w", err)}
+		}
+		return tagsSavedMsg{bookmarks: renamed, from: from, to: to, skipped: skipped}
+	}
+}
+
+// tagEditorModel edits the comma-separated tags of a bookmark, or renames a tag on all bookmarks
+type tagEditorModel struct {
+	ctx    context.Context
+	client instapaper.Client
+	input  textinput.Model
+	// tags are the known tags, suggested while typing
+	tags []string
+	// bookmark has the tags being edited, unless a tag is renamed, folderID is where it is listed
+	bookmark instapaper.Bookmark
+	folderID string
+	rename   string
+	saving   bool
+	err      error
+	width    int
+	height   int
+}
+
+func newTagEditorModel() tagEditorModel {
+	m := tagEditorModel{input: textinput.New()}
+	m.input.ShowSuggestions = true
+	m.input.KeyMap.AcceptSuggestion = tagEditorKeys.complete
+	return m
+}
+
+// editBookmark opens the editor with the tags of a bookmark
+func (m tagEditorModel) editBookmark(ctx context.Context, client instapaper.Client, bookmark instapaper.Bookmark, folderID string, tags []string) (tagEditorModel, tea.Cmd) {
+	m = m.reset(ctx, client, tags)
+	m.bookmark = bookmark
+	m.folderID = folderID
+	m.input.Prompt = "Tags: "
+	m.input.Placeholder = "comma separated"
+	if names := instapaper.TagNames(bookmark.Tags); len(names) > 0 {
+		m.input.SetValue(strings.Join(names, ", ") + ", ")
+	}
+	m.suggest()
+	return m, m.input.Focus()
+}
+
+// renameTag opens the editor to rename a tag. Renaming to a tag that exists merges them.
+func (m tagEditorModel) renameTag(ctx context.Context, client instapaper.Client, tag string, tags []string) (tagEditorModel, tea.Cmd) {
+	m = m.reset(ctx, client, tags)
+	m.rename = tag
+	m.input.Prompt = "New name: "
+	m.input.Placeholder = tag
+	m.suggest()
+	return m, m.input.Focus()
+}
+
+func (m tagEditorModel) reset(ctx context.Context, client instapaper.Client, tags []string) tagEditorModel {
+	m.ctx = ctx
+	m.client = client
+	m.tags = tags
+	m.bookmark = instapaper.Bookmark{}
+	m.folderID = ""
+	m.rename = ""
+	m.saving = false
+	m.err = nil
+	m.input.Reset()
+	return m
+}
+
+func (m *tagEditorModel) setSize(width, height int) {
+	m.width = width
+	m.height = height
+	m.input.Width = width / 2
+}
+
+func (m tagEditorModel) Update(msg tea.Msg) (tagEditorModel, tea.Cmd) {
+	var cmd tea.Cmd
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if m.saving {
+			return m, nil
+		}
+		if key.Matches(msg, tagEditorKeys.submit) {
+			return m.submit()
+		}
+	case tagsErrMsg:
+		m.saving = false
+		m.err = msg.err
+		return m, nil
+	}
+	m.input, cmd = m.input.Update(msg)
+	m.suggest()
+	return m, cmd
+}
+
+// suggest completes the tag being typed with the known tags that are not entered yet.
+// The text input only matches suggestions against the whole value.
+func (m *tagEditorModel) suggest() {
+	if m.rename != "" {
+		m.input.SetSuggestions(slices.DeleteFunc(slices.Clone(m.tags), func(t string) bool { return t == m.rename }))
+		return
+	}
+	value := m.input.Value()
+	entered := parseTags(value)
+	prefix := value[:strings.LastIndex(value, ",")+1]
+	if rest := value[len(prefix):]; strings.TrimSpace(rest) == "" {
+		prefix = value
+	} else {
+		prefix += rest[:len(rest)-len(strings.TrimLeft(rest, " "))]
+	}
+	suggestions := []string{}
+	for _, tag := range m.tags {
+		if !slices.Contains(entered, tag) {
+			suggestions = append(suggestions, prefix+tag)
+		}
+	}
+	m.input.SetSuggestions(suggestions)
+}
+
+func (m tagEditorModel) submit() (tagEditorModel, tea.Cmd) {
+	m.err = nil
+	if m.rename != "" {
+		to := strings.TrimSpace(m.input.Value())
+		switch {
+		case to == "":
+			m.err = errors.New("the new name is required")
+			return m, nil
+		case strings.Contains(to, ","):
+			m.err = errors.New("tags cannot contain commas")
+			return m, nil
+		}
+		m.saving = true
+		return m, renameTag(m.ctx, m.client, m.rename, to)
+	}
+	m.saving = true
+	return m, setTags(m.ctx, m.client, m.bookmark, m.folderID, parseTags(m.input.Value()))
+}
+
+func (m tagEditorModel) View() string {
+	status := ""
+	switch {
+	case m.saving && m.rename != "":
+		status = "Renaming on all bookmarks..."
+	case m.saving:
+		status = "Saving..."
+	case m.err != nil:
+		status = errStyle.Width(m.width / 2).Render(m.err.Error())
+	}
+	title := "Tags of " + m.bookmark.Title
+	if m.rename != "" {
+		title = fmt.Sprintf("Rename tag %q", m.rename)
+	}
+	fields := []string{loginTitleStyle.Render(title), m.input.View(), "", status}
+	form := addBookmarkFormStyle.Render(lipgloss.JoinVertical(lipgloss.Left, fields...))
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, form)
+}
+
+func (m tagEditorModel) ShortHelp() []key.Binding {
+	return []key.Binding{tagEditorKeys.complete, tagEditorKeys.submit, tagEditorKeys.cancel}
+}
+
+func (m tagEditorModel) FullHelp() [][]key.Binding {
+	return [][]key.Binding{m.ShortHelp()}
+}
+
+// misc helper functions
+
+// parseTags returns the comma-separated tags without blanks and duplicates
+func parseTags(value string) []string {
+	tags := []string{}
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
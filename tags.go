@@ -0,0 +1,273 @@
+// tag filtering
+package main
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ieroNo47/gopaper/internal/instapaper"
+)
+
+type tagKeyMap struct {
+	toggle    key.Binding
+	matchMode key.Binding
+	clear     key.Binding
+	edit      key.Binding
+	rename    key.Binding
+}
+
+var tagKeys = tagKeyMap{
+	toggle: key.NewBinding(
+		key.WithKeys("enter", " "),
+		key.WithHelp("enter", "filter by tag"),
+	),
+	matchMode: key.NewBinding(
+		key.WithKeys("o"),
+		key.WithHelp("o", "match all/any"),
+	),
+	clear: key.NewBinding(
+		key.WithKeys("c"),
+		key.WithHelp("c", "clear tags"),
+	),
+	edit: key.NewBinding(
+		key.WithKeys("t"),
+		key.WithHelp("t", "edit tags"),
+	),
+	rename: key.NewBinding(
+		key.WithKeys("r"),
+		key.WithHelp("r", "rename"),
+	),
+}
+
+// the columns of a tag row
+const (
+	tagMarkColumn = iota
+	tagNameColumn
+	tagCountColumn
+)
+
+// tagColumns returns the columns of the tags table for its width.
+// The cells are padded by 1 on both sides.
+func tagColumns(width int) []table.Column {
+	return []table.Column{
+		tagMarkColumn:  {Width: 1},
+		tagNameColumn:  {Width: max(width-1-4-2*2, 1)},
+		tagCountColumn: {Width: 4},
+	}
+}
+
+// tagFilter lists only the bookmarks with the selected tags
+type tagFilter struct {
+	// tags are the selected tag names in the order they were selected
+	tags []string
+	// any matches bookmarks with at least one of the tags instead of all of them
+	any bool
+}
+
+func (f tagFilter) active() bool {
+	return len(f.tags) > 0
+}
+
+// toggle selects or deselects a tag
+func (f tagFilter) toggle(tag string) tagFilter {
+	if i := slices.Index(f.tags, tag); i >= 0 {
+		f.tags = slices.Delete(slices.Clone(f.tags), i, i+1)
+	} else {
+		f.tags = append(slices.Clone(f.tags), tag)
+	}
+	return f
+}
+
+func (f tagFilter) matches(i item) bool {
+	if !f.active() {
+		return true
+	}
+	for _, tag := range f.tags {
+		has := slices.ContainsFunc(i.Tags(), func(t instapaper.Tag) bool { return t.Name == tag })
+		if has && f.any {
+			return true
+		}
+		if !has && !f.any {
+			return false
+		}
+	}
+	return !f.any
+}
+
+// rename replaces a renamed tag, which is dropped if the new name is selected too
+func (f tagFilter) rename(from, to string) tagFilter {
+	i := slices.Index(f.tags, from)
+	if i < 0 {
+		return f
+	}
+	f.tags = slices.Clone(f.tags)
+	if slices.Contains(f.tags, to) {
+		f.tags = slices.Delete(f.tags, i, i+1)
+	} else {
+		f.tags[i] = to
+	}
+	return f
+}
+
+func (f tagFilter) String() string {
+	op := " and "
+	if f.any {
+		op = " or "
+	}
+	return strings.Join(f.tags, op)
+}
+
+// filterItems returns the items matching the tag filter
+func (m model) filterItems(items []list.Item) []list.Item {
+	if !m.tagFilter.active() {
+		return items
+	}
+	filtered := []list.Item{}
+	for _, li := range items {
+		if m.tagFilter.matches(li.(item)) {
+			filtered = append(filtered, li)
+		}
+	}
+	return filtered
+}
+
+// setTagFilter lists the bookmarks of the current folder that match a new filter
+func (m *model) setTagFilter(f tagFilter) tea.Cmd {
+	m.tagFilter = f
+	m.table.SetRows(m.getTagRows())
+	cmd := m.list.SetItems(m.filterItems(libraryItems(m.library())))
+	m.list.ResetSelected()
+	return cmd
+}
+
+// updateTags handles the keys of the tags table
+func (m *model) updateTags(msg tea.KeyMsg) tea.Cmd {
+	var cmd tea.Cmd
+	switch {
+	case key.Matches(msg, tagKeys.toggle):
+		row := m.table.SelectedRow()
+		if len(row) <= tagNameColumn {
+			return nil
+		}
+		cursor := m.table.Cursor()
+		cmd = m.setTagFilter(m.tagFilter.toggle(row[tagNameColumn]))
+		m.table.SetCursor(cursor)
+	case key.Matches(msg, tagKeys.matchMode):
+		f := m.tagFilter
+		f.any = !f.any
+		cmd = m.setTagFilter(f)
+	case key.Matches(msg, tagKeys.clear):
+		cmd = m.setTagFilter(tagFilter{any: m.tagFilter.any})
+	case key.Matches(msg, tagKeys.rename) && m.ready:
+		row := m.table.SelectedRow()
+		if len(row) <= tagNameColumn || row[tagNameColumn] == "" {
+			return nil
+		}
+		m.state = tagEditorView
+		m.tagEditor, cmd = m.tagEditor.renameTag(m.ctx, m.client, row[tagNameColumn], m.tagNames())
+	default:
+		m.table, cmd = m.table.Update(msg)
+	}
+	return cmd
+}
+
+// applyTags puts bookmarks with edited tags into the libraries, the cache and the list
+func (m *model) applyTags(bookmarks []instapaper.Bookmark) tea.Cmd {
+	if len(bookmarks) == 0 {
+		return nil
+	}
+	for folderID, library := range m.libraries {
+		changed := false
+		for _, bookmark := range bookmarks {
+			changed = library.Update(bookmark) || changed
+		}
+		if changed {
+			_ = m.cache.SaveBookmarks(folderID, library.Bookmarks())
+		}
+	}
+	// the bookmarks may no longer match the filter, or match it now
+	index := m.list.Index()
+	cmd := m.setTagFilter(m.tagFilter)
+	if n := len(m.list.Items()); n > 0 {
+		m.list.Select(min(index, n-1))
+	}
+	return cmd
+}
+
+// tagEditorReturn is the view the tag editor was opened from
+func (m model) tagEditorReturn() sessionState {
+	if m.tagEditor.rename != "" {
+		return tagsView
+	}
+	return bookmarksView
+}
+
+// tagStatus shows the tag filter of the list
+func (m model) tagStatus() string {
+	if !m.tagFilter.active() {
+		return ""
+	}
+	return " • tagged " + m.tagFilter.String()
+}
+
+// bookmarkFolder is the ID of the folder a listed bookmark is in. Starred bookmarks are looked up
+// in the synced folders, the ones that are not found are taken to be in Home.
+func (m model) bookmarkFolder(bookmarkID int64) string {
+	if m.folderID != instapaper.FolderStarred {
+		return m.folderID
+	}
+	for folderID, library := range m.libraries {
+		if folderID != instapaper.FolderStarred && slices.ContainsFunc(library.Bookmarks(), func(b instapaper.Bookmark) bool { return b.BookmarkID == bookmarkID }) {
+			return folderID
+		}
+	}
+	return instapaper.FolderUnread
+}
+
+// getTags returns the tags of the bookmarks in the current folder and their counts.
+// The Instapaper API has no endpoint for the tags of the user.
+func (m model) getTags() map[string]int {
+	tags := map[string]int{}
+	for _, bookmark := range m.library().Bookmarks() {
+		for _, tag := range bookmark.Tags {
+			tags[tag.Name]++
+		}
+	}
+	return tags
+}
+
+// tagNames returns the tags of the bookmarks in the current folder, most used first
+func (m model) tagNames() []string {
+	tags := m.getTags()
+	names := make([]string, 0, len(tags))
+	for name := range tags {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if tags[names[i]] != tags[names[j]] {
+			return tags[names[i]] > tags[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// getTagRows returns a row for each tag, most used first. Selected tags are marked.
+func (m model) getTagRows() []table.Row {
+	tags := m.getTags()
+	rows := []table.Row{}
+	for _, name := range m.tagNames() {
+		mark := " "
+		if slices.Contains(m.tagFilter.tags, name) {
+			mark = "✓"
+		}
+		rows = append(rows, table.Row{tagMarkColumn: mark, tagNameColumn: name, tagCountColumn: strconv.Itoa(tags[name])})
+	}
+	return rows
+}